@@ -15,6 +15,8 @@ import (
 	"github.com/spf13/viper"
 )
 
+var MigrationsOutOfOrder string
+
 // applyCmd represents the apply command
 var applyCmd = &cobra.Command{
 	Use:   "apply",
@@ -23,8 +25,16 @@ var applyCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		migrationFolder := viper.GetString("migrations-directory")
 		migrationIdentifier := viper.GetString("migrations-identifier")
+		migrationRecursive := viper.GetBool("migrations-recursive")
 
-		loadedMigrations, err := migrations.LoadMigrationsDirectory(migrationFolder, migrationIdentifier)
+		migrationFormat, err := migrations.GetMigrationFormat(viper.GetString("migrations-format"))
+
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+
+		loadedMigrations, err := migrations.LoadMigrationsDirectory(migrationFolder, migrationIdentifier, migrationRecursive, migrationFormat)
 
 		if err != nil {
 			slog.Error("Could not load migrations files", "error", err)
@@ -68,6 +78,15 @@ var applyCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		outOfOrderPolicy, err := migrations.ParseOutOfOrderPolicy(viper.GetString("out-of-order"))
+
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+
+		pendingMigrations := make([]migrations.Migration, 0)
+
 		for _, migration := range loadedMigrations {
 			if migration.MigrationSide == migrations.MigrationUp {
 				isMigrationApplied, err := migration.CheckIfMigrationIsApplied(conn, migrationDatabase, migrationTable)
@@ -82,20 +101,44 @@ var applyCmd = &cobra.Command{
 					continue
 				}
 
-				fmt.Printf("Applying migration %s\n", migration.Path)
-				err = migration.Apply(conn)
+				pendingMigrations = append(pendingMigrations, migration)
+			}
+		}
 
-				if err != nil {
-					slog.Error(fmt.Sprintf("Error applying migration %s: %s", migration.Path, err.Error()))
-					os.Exit(1)
-				}
+		latestApplied, hasApplied, err := migrations.GetLatestAppliedMigrationDatetime(conn, migrationDatabase, migrationTable, migrationIdentifier)
 
-				err = migration.StoreMigration(conn, migrationDatabase, migrationTable)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error getting latest applied migration: %s", err.Error()))
+			os.Exit(1)
+		}
 
-				if err != nil {
-					slog.Error(fmt.Sprintf("Error storing migration %s: %s", migration.Path, err.Error()))
-					os.Exit(1)
-				}
+		if hasApplied && outOfOrderPolicy != migrations.OutOfOrderAllow {
+			outOfOrderMigrations := migrations.FindOutOfOrderMigrations(pendingMigrations, latestApplied)
+
+			for _, migration := range outOfOrderMigrations {
+				slog.Warn("Pending migration is older than the latest applied migration", "migration", migration.Path, "datetime", migration.Datetime, "latest_applied", latestApplied)
+			}
+
+			if len(outOfOrderMigrations) > 0 && outOfOrderPolicy == migrations.OutOfOrderError {
+				slog.Error(fmt.Sprintf("Found %d out-of-order migrations, use --out-of-order=warn or --out-of-order=allow to apply them anyway", len(outOfOrderMigrations)))
+				os.Exit(1)
+			}
+		}
+
+		for _, migration := range pendingMigrations {
+			fmt.Printf("Applying migration %s\n", migration.Path)
+			err = migration.Apply(conn)
+
+			if err != nil {
+				slog.Error(fmt.Sprintf("Error applying migration %s: %s", migration.Path, err.Error()))
+				os.Exit(1)
+			}
+
+			err = migration.StoreMigration(conn, migrationDatabase, migrationTable)
+
+			if err != nil {
+				slog.Error(fmt.Sprintf("Error storing migration %s: %s", migration.Path, err.Error()))
+				os.Exit(1)
 			}
 		}
 	},
@@ -103,4 +146,8 @@ var applyCmd = &cobra.Command{
 
 func init() {
 	migrationsCmd.AddCommand(applyCmd)
+
+	applyCmd.Flags().StringVar(&MigrationsOutOfOrder, "out-of-order", "warn", "Policy for pending migrations older than the latest applied one: error, warn or allow")
+	viper.BindPFlag("out-of-order", applyCmd.Flags().Lookup("out-of-order"))
+	viper.BindEnv("out-of-order", "CLICKHOUSE_MIGRATIONS_OUT_OF_ORDER")
 }
@@ -10,10 +10,13 @@ import (
 	"strings"
 	"time"
 
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/migrations"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
+var MigrationsSingleFile bool
+
 // createCmd represents the create command
 var createCmd = &cobra.Command{
 	Use:   "create",
@@ -26,6 +29,11 @@ var createCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		if migrationFormat := viper.GetString("migrations-format"); migrationFormat != migrations.DefaultMigrationFormat {
+			slog.Error(fmt.Sprintf("Creating migrations is only supported for the %s format, not %s", migrations.DefaultMigrationFormat, migrationFormat))
+			os.Exit(1)
+		}
+
 		migrationFolder := viper.GetString("migrations-directory")
 
 		slog.Info("Creating migration", "name", args[0])
@@ -35,6 +43,20 @@ var createCmd = &cobra.Command{
 
 		creationDatetime := fmt.Sprintf("%s_%s", creationDate, strings.ReplaceAll(creationTime, ":", "-"))
 
+		if viper.GetBool("single-file") {
+			migrationFile := fmt.Sprintf("%s/%s_%s.sql", migrationFolder, creationDatetime, args[0])
+
+			err := os.WriteFile(migrationFile, []byte(fmt.Sprintf("%s\n\n%s\n", migrations.SingleFileUpMarker, migrations.SingleFileDownMarker)), 0644)
+
+			if err != nil {
+				slog.Error("Failed to create migration file", "error", err)
+				os.Exit(1)
+			}
+
+			slog.Info("Created migration file", "file", migrationFile)
+			return
+		}
+
 		upMigrationFile := fmt.Sprintf("%s/%s_%s.up.sql", migrationFolder, creationDatetime, args[0])
 
 		_, err := os.Create(upMigrationFile)
@@ -61,4 +83,7 @@ var createCmd = &cobra.Command{
 
 func init() {
 	migrationsCmd.AddCommand(createCmd)
+
+	createCmd.Flags().BoolVar(&MigrationsSingleFile, "single-file", false, "Create a single migration file with -- +up and -- +down sections")
+	viper.BindPFlag("single-file", createCmd.Flags().Lookup("single-file"))
 }
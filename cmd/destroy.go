@@ -23,8 +23,16 @@ var destroyCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		migrationFolder := viper.GetString("migrations-directory")
 		migrationIdentifier := viper.GetString("migrations-identifier")
+		migrationRecursive := viper.GetBool("migrations-recursive")
 
-		loadedMigrations, err := migrations.LoadMigrationsDirectory(migrationFolder, migrationIdentifier)
+		migrationFormat, err := migrations.GetMigrationFormat(viper.GetString("migrations-format"))
+
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+
+		loadedMigrations, err := migrations.LoadMigrationsDirectory(migrationFolder, migrationIdentifier, migrationRecursive, migrationFormat)
 
 		if err != nil {
 			slog.Error("Could not load migrations files", "error", err)
@@ -0,0 +1,94 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/clickhouse_wrapper"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var DictionaryCommandDatabase string
+
+// dictionaryCmd represents the dictionary command
+var dictionaryCmd = &cobra.Command{
+	Use:   "dictionary",
+	Short: "Subcommands for managing ClickHouse dictionaries",
+	Long: `Subcommands for managing ClickHouse dictionaries.
+
+Dictionaries are given as database.name, or as name in the database of the
+--database flag.`,
+}
+
+func init() {
+	rootCmd.AddCommand(dictionaryCmd)
+
+	dictionaryCmd.PersistentFlags().StringVar(&DictionaryCommandDatabase, "database", "", "ClickHouse database of the dictionaries (every database when listing, default otherwise)")
+	viper.BindPFlag("dictionary-command-database", dictionaryCmd.PersistentFlags().Lookup("database"))
+	viper.BindEnv("dictionary-command-database", "CLICKHOUSE_DICTIONARY_DATABASE")
+}
+
+// Connect to ClickHouse with the global flags, exiting on failure.
+func connectDictionaryCommand() driver.Conn {
+	clickhouseAddress := viper.GetString("clickhouse-address")
+	clickhouseUsername := viper.GetString("clickhouse-username")
+	clickhousePassword := viper.GetString("clickhouse-password")
+
+	conn, err := clickhouse_wrapper.ConnectToClickhouse(clickhouseAddress, clickhouseUsername, clickhousePassword)
+
+	if err != nil {
+		slog.Error(fmt.Sprintf("Error connecting to Clickhouse: %s", err.Error()))
+		os.Exit(1)
+	}
+
+	return conn
+}
+
+// Database and name of a dictionary given as an argument.
+func dictionaryArgument(argument string) (string, string) {
+	database, name := clickhouse_wrapper.SplitTableName(argument)
+
+	if database == "" {
+		database = viper.GetString("dictionary-command-database")
+	}
+
+	if database == "" {
+		database = "default"
+	}
+
+	return database, name
+}
+
+func formatBytes(bytes uint64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	size := float64(bytes)
+	unit := 0
+
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	return fmt.Sprintf("%.2f %s", size, units[unit])
+}
+
+// Time reported by `system.dictionaries`, which is the epoch when the
+// dictionary was never loaded.
+func formatDictionaryTime(value time.Time) string {
+	if value.Unix() <= 0 {
+		return "never"
+	}
+
+	return value.Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,85 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/clickhouse_wrapper"
+	"github.com/spf13/cobra"
+)
+
+// dictionaryDescribeCmd represents the dictionary describe command
+var dictionaryDescribeCmd = &cobra.Command{
+	Use:   "describe <dictionary>",
+	Short: "Describe the structure of a dictionary",
+	Long:  `Describe the layout, key, attributes, source and lifetime of a dictionary.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dictionaryDatabase, dictionaryName := dictionaryArgument(args[0])
+
+		conn := connectDictionaryCommand()
+
+		defer conn.Close()
+
+		info, err := clickhouse_wrapper.GetDictionaryInfo(conn, dictionaryDatabase, dictionaryName)
+
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error describing dictionary: %s", err.Error()))
+			os.Exit(1)
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+		fmt.Fprintf(writer, "Dictionary:\t%s.%s\n", info.Database, info.Name)
+		fmt.Fprintf(writer, "Layout:\t%s\n", info.Type)
+
+		if info.LifetimeMin == info.LifetimeMax {
+			fmt.Fprintf(writer, "Lifetime:\t%ds\n", info.LifetimeMax)
+		} else {
+			fmt.Fprintf(writer, "Lifetime:\t%ds to %ds\n", info.LifetimeMin, info.LifetimeMax)
+		}
+
+		fmt.Fprintf(writer, "Source:\t%s\n", info.Source)
+
+		// The description of system.dictionaries does not tell the database of
+		// the source table when it is the one of the dictionary
+		if source, err := clickhouse_wrapper.GetDictionarySource(conn, dictionaryDatabase, dictionaryName); err == nil && source.Table != "" {
+			fmt.Fprintf(writer, "Source table:\t%s\n", source.QualifiedTable())
+
+			if source.Where != "" {
+				fmt.Fprintf(writer, "Source filter:\t%s\n", source.Where)
+			}
+		}
+
+		fmt.Fprintln(writer, "\nKEY\tTYPE")
+
+		for i, name := range info.KeyNames {
+			fmt.Fprintf(writer, "%s\t%s\n", name, typeAt(info.KeyTypes, i))
+		}
+
+		fmt.Fprintln(writer, "\nATTRIBUTE\tTYPE")
+
+		for i, name := range info.AttributeNames {
+			fmt.Fprintf(writer, "%s\t%s\n", name, typeAt(info.AttributeTypes, i))
+		}
+
+		writer.Flush()
+	},
+}
+
+func typeAt(types []string, index int) string {
+	if index < len(types) {
+		return types[index]
+	}
+
+	return ""
+}
+
+func init() {
+	dictionaryCmd.AddCommand(dictionaryDescribeCmd)
+}
@@ -0,0 +1,59 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/clickhouse_wrapper"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// dictionaryListCmd represents the dictionary list command
+var dictionaryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the dictionaries of Clickhouse",
+	Long: `List the dictionaries of Clickhouse, with their status, element count, memory
+usage and last successful update.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		conn := connectDictionaryCommand()
+
+		defer conn.Close()
+
+		dictionaries, err := clickhouse_wrapper.ListDictionaries(conn, viper.GetString("dictionary-command-database"))
+
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error listing dictionaries: %s", err.Error()))
+			os.Exit(1)
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+		fmt.Fprintln(writer, "DICTIONARY\tSTATUS\tELEMENTS\tMEMORY\tLAST UPDATE")
+
+		for _, dictionary := range dictionaries {
+			fmt.Fprintf(
+				writer,
+				"%s.%s\t%s\t%d\t%s\t%s\n",
+				dictionary.Database,
+				dictionary.Name,
+				dictionary.Status,
+				dictionary.ElementCount,
+				formatBytes(dictionary.BytesAllocated),
+				formatDictionaryTime(dictionary.LastSuccessfulUpdateTime),
+			)
+		}
+
+		writer.Flush()
+	},
+}
+
+func init() {
+	dictionaryCmd.AddCommand(dictionaryListCmd)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/clickhouse_wrapper"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var DictionaryReloadAll bool
+var DictionaryReloadFailed bool
+
+// dictionaryReloadCmd represents the dictionary reload command
+var dictionaryReloadCmd = &cobra.Command{
+	Use:   "reload [dictionary...]",
+	Short: "Reload dictionaries",
+	Long: `Reload the given dictionaries, every dictionary with --all, or the dictionaries
+which failed to load with --failed. --all and --failed are restricted to the
+database of the --database flag when it is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		reloadAll := viper.GetBool("dictionary-reload-all")
+		reloadFailed := viper.GetBool("dictionary-reload-failed")
+
+		if len(args) == 0 && !reloadAll && !reloadFailed {
+			cmd.Help()
+			slog.Error("No dictionary provided: give dictionaries, --all or --failed")
+			os.Exit(1)
+		}
+
+		if len(args) > 0 && (reloadAll || reloadFailed) {
+			slog.Error("Dictionaries cannot be given with --all or --failed")
+			os.Exit(1)
+		}
+
+		conn := connectDictionaryCommand()
+
+		defer conn.Close()
+
+		type dictionaryName struct {
+			database string
+			name     string
+		}
+
+		dictionaries := make([]dictionaryName, 0)
+
+		for _, arg := range args {
+			database, name := dictionaryArgument(arg)
+			dictionaries = append(dictionaries, dictionaryName{database, name})
+		}
+
+		if reloadAll || reloadFailed {
+			listed, err := clickhouse_wrapper.ListDictionaries(conn, viper.GetString("dictionary-command-database"))
+
+			if err != nil {
+				slog.Error(fmt.Sprintf("Error listing dictionaries: %s", err.Error()))
+				os.Exit(1)
+			}
+
+			for _, dictionary := range listed {
+				if reloadAll || dictionary.Status == "FAILED" || dictionary.Status == "FAILED_AND_RELOADING" {
+					dictionaries = append(dictionaries, dictionaryName{dictionary.Database, dictionary.Name})
+				}
+			}
+		}
+
+		failed := 0
+
+		for _, dictionary := range dictionaries {
+			if err := clickhouse_wrapper.ReloadDictionary(conn, dictionary.database, dictionary.name); err != nil {
+				fmt.Printf("FAILED  %s.%s: %s\n", dictionary.database, dictionary.name, err)
+				failed++
+				continue
+			}
+
+			status, err := clickhouse_wrapper.GetDictionaryStatus(conn, dictionary.database, dictionary.name)
+
+			if err != nil {
+				fmt.Printf("FAILED  %s.%s: %s\n", dictionary.database, dictionary.name, err)
+				failed++
+				continue
+			}
+
+			fmt.Printf("OK      %s.%s: %s, %d elements\n", dictionary.database, dictionary.name, status.Status, status.ElementCount)
+		}
+
+		if failed > 0 {
+			slog.Error(fmt.Sprintf("%d of %d dictionaries failed to reload", failed, len(dictionaries)))
+			os.Exit(1)
+		}
+
+		slog.Info(fmt.Sprintf("Reloaded %d dictionaries", len(dictionaries)))
+	},
+}
+
+func init() {
+	dictionaryCmd.AddCommand(dictionaryReloadCmd)
+
+	dictionaryReloadCmd.Flags().BoolVar(&DictionaryReloadAll, "all", false, "Reload every dictionary")
+	viper.BindPFlag("dictionary-reload-all", dictionaryReloadCmd.Flags().Lookup("all"))
+
+	dictionaryReloadCmd.Flags().BoolVar(&DictionaryReloadFailed, "failed", false, "Reload the dictionaries which failed to load")
+	viper.BindPFlag("dictionary-reload-failed", dictionaryReloadCmd.Flags().Lookup("failed"))
+}
@@ -0,0 +1,59 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/clickhouse_wrapper"
+	"github.com/spf13/cobra"
+)
+
+// dictionaryStatusCmd represents the dictionary status command
+var dictionaryStatusCmd = &cobra.Command{
+	Use:   "status <dictionary>",
+	Short: "Show the loading status of a dictionary",
+	Long:  `Show the loading status of a dictionary, with its last exception if it failed to load.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dictionaryDatabase, dictionaryName := dictionaryArgument(args[0])
+
+		conn := connectDictionaryCommand()
+
+		defer conn.Close()
+
+		info, err := clickhouse_wrapper.GetDictionaryInfo(conn, dictionaryDatabase, dictionaryName)
+
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error getting dictionary status: %s", err.Error()))
+			os.Exit(1)
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+		fmt.Fprintf(writer, "Dictionary:\t%s.%s\n", info.Database, info.Name)
+		fmt.Fprintf(writer, "Status:\t%s\n", info.Status)
+		fmt.Fprintf(writer, "Elements:\t%d\n", info.ElementCount)
+		fmt.Fprintf(writer, "Memory:\t%s\n", formatBytes(info.BytesAllocated))
+		fmt.Fprintf(writer, "Queries:\t%d\n", info.QueryCount)
+		fmt.Fprintf(writer, "Hit rate:\t%.2f%%\n", info.HitRate*100)
+		fmt.Fprintf(writer, "Load factor:\t%.2f%%\n", info.LoadFactor*100)
+		fmt.Fprintf(writer, "Loading start:\t%s\n", formatDictionaryTime(info.LoadingStartTime))
+		fmt.Fprintf(writer, "Loading duration:\t%s\n", info.LoadingDuration)
+		fmt.Fprintf(writer, "Last update:\t%s\n", formatDictionaryTime(info.LastSuccessfulUpdateTime))
+
+		if info.LastException != "" {
+			fmt.Fprintf(writer, "Last exception:\t%s\n", info.LastException)
+		}
+
+		writer.Flush()
+	},
+}
+
+func init() {
+	dictionaryCmd.AddCommand(dictionaryStatusCmd)
+}
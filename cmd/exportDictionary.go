@@ -0,0 +1,138 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/clickhouse_wrapper"
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/dictionary_data"
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/dictionary_sync"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var ExportDictionaryDatabase string
+var ExportDictionaryName string
+var ExportFrom string
+var ExportFormat string
+var ExportOutput string
+var ExportCSVDelimiter string
+
+// exportDictionaryCmd represents the exportDictionary command
+var exportDictionaryCmd = &cobra.Command{
+	Use:   "exportDictionary",
+	Short: "Export the data of a dictionary from Clickhouse",
+	Long: `Export the data of a dictionary from Clickhouse.
+
+Writes the content of the dictionary source table, or of the dictionary itself
+with --from dictionary, sorted by the dictionary key, in a format read by
+syncDictionary: json, ndjson, csv or tsv.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dictionaryDatabase := viper.GetString("export-dictionary-database")
+		dictionaryName := viper.GetString("export-dictionary-name")
+
+		if dictionaryDatabase == "" || dictionaryName == "" {
+			cmd.Help()
+			slog.Error("No dictionary database or name provided")
+			os.Exit(1)
+		}
+
+		from := viper.GetString("export-from")
+
+		if from != "source" && from != "dictionary" {
+			slog.Error(fmt.Sprintf("Unknown export origin '%s': expected source or dictionary", from))
+			os.Exit(1)
+		}
+
+		output := viper.GetString("export-output")
+		format := viper.GetString("export-format")
+
+		if format == "" {
+			format = dictionary_data.DetectFormat(output)
+		}
+
+		delimiter := []rune(viper.GetString("export-csv-delimiter"))
+
+		if len(delimiter) != 1 {
+			slog.Error("CSV delimiter must be a single character")
+			os.Exit(1)
+		}
+
+		clickhouseAddress := viper.GetString("clickhouse-address")
+		clickhouseUsername := viper.GetString("clickhouse-username")
+		clickhousePassword := viper.GetString("clickhouse-password")
+
+		conn, err := clickhouse_wrapper.ConnectToClickhouse(clickhouseAddress, clickhouseUsername, clickhousePassword)
+
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error connecting to Clickhouse: %s", err.Error()))
+			os.Exit(1)
+		}
+
+		defer conn.Close()
+
+		var writer io.Writer = os.Stdout
+
+		if output != "-" {
+			file, err := os.Create(output)
+
+			if err != nil {
+				slog.Error(fmt.Sprintf("Error creating output file: %s", err.Error()))
+				os.Exit(1)
+			}
+
+			defer file.Close()
+
+			writer = file
+		}
+
+		exported, err := dictionary_sync.Export(conn, dictionary_sync.ExportOptions{
+			DictionaryDatabase: dictionaryDatabase,
+			DictionaryName:     dictionaryName,
+			FromDictionary:     from == "dictionary",
+			Format:             format,
+			CSVDelimiter:       delimiter[0],
+		}, writer)
+
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error exporting dictionary: %s", err.Error()))
+
+			if output != "-" {
+				os.Remove(output)
+			}
+
+			os.Exit(1)
+		}
+
+		slog.Info(fmt.Sprintf("Exported %d rows of dictionary %s.%s", exported, dictionaryDatabase, dictionaryName))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportDictionaryCmd)
+
+	exportDictionaryCmd.Flags().StringVar(&ExportDictionaryDatabase, "dictionary-database", "", "ClickHouse Dictionary database to export")
+	viper.BindPFlag("export-dictionary-database", exportDictionaryCmd.Flags().Lookup("dictionary-database"))
+	viper.BindEnv("export-dictionary-database", "CLICKHOUSE_DICTIONARY_DATABASE")
+
+	exportDictionaryCmd.Flags().StringVar(&ExportDictionaryName, "dictionary-name", "", "ClickHouse Dictionary name to export")
+	viper.BindPFlag("export-dictionary-name", exportDictionaryCmd.Flags().Lookup("dictionary-name"))
+	viper.BindEnv("export-dictionary-name", "CLICKHOUSE_DICTIONARY_NAME")
+
+	exportDictionaryCmd.Flags().StringVar(&ExportFrom, "from", "source", "Data to export: the dictionary source table (source) or the data loaded in the dictionary (dictionary)")
+	viper.BindPFlag("export-from", exportDictionaryCmd.Flags().Lookup("from"))
+
+	exportDictionaryCmd.Flags().StringVar(&ExportFormat, "format", "", "Format of the exported data: json, ndjson, csv or tsv (detected from the output extension by default)")
+	viper.BindPFlag("export-format", exportDictionaryCmd.Flags().Lookup("format"))
+
+	exportDictionaryCmd.Flags().StringVar(&ExportOutput, "output", "-", "File to write the exported data to, - for stdout")
+	viper.BindPFlag("export-output", exportDictionaryCmd.Flags().Lookup("output"))
+
+	exportDictionaryCmd.Flags().StringVar(&ExportCSVDelimiter, "csv-delimiter", ",", "Field delimiter of the CSV output")
+	viper.BindPFlag("export-csv-delimiter", exportDictionaryCmd.Flags().Lookup("csv-delimiter"))
+}
@@ -0,0 +1,171 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/clickhouse_wrapper"
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/migrations"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var ImportHistoryFrom string
+var ImportHistoryFromDatabase string
+var ImportHistoryFromTable string
+var ImportHistoryDryRun bool
+
+// importHistoryCmd represents the import-history command
+var importHistoryCmd = &cobra.Command{
+	Use:   "import-history",
+	Short: "Import the migration history of another migration tool",
+	Long: `Import the migration history of another migration tool.
+
+Reads the versions applied by golang-migrate, goose or clickhouse-migrations
+from their bookkeeping table, and records the matching migration files in the
+migrations table, so that they are not applied again.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		source := viper.GetString("import-history-from")
+
+		defaultSourceTable, ok := migrations.HistorySourceTables[source]
+
+		if !ok {
+			cmd.Help()
+			slog.Error(fmt.Sprintf("Unknown history source '%s': expected one of golang-migrate, goose, clickhouse-migrations", source))
+			os.Exit(1)
+		}
+
+		sourceDatabase := viper.GetString("import-history-from-database")
+		sourceTable := viper.GetString("import-history-from-table")
+
+		if sourceTable == "" {
+			sourceTable = defaultSourceTable
+		}
+
+		migrationFolder := viper.GetString("migrations-directory")
+		migrationIdentifier := viper.GetString("migrations-identifier")
+		migrationRecursive := viper.GetBool("migrations-recursive")
+
+		migrationFormat, err := migrations.GetMigrationFormat(viper.GetString("migrations-format"))
+
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+
+		loadedMigrations, err := migrations.LoadMigrationsDirectory(migrationFolder, migrationIdentifier, migrationRecursive, migrationFormat)
+
+		if err != nil {
+			slog.Error("Could not load migrations files", "error", err)
+			os.Exit(1)
+		}
+
+		clickhouseAddress := viper.GetString("clickhouse-address")
+		clickhouseUsername := viper.GetString("clickhouse-username")
+		clickhousePassword := viper.GetString("clickhouse-password")
+
+		slog.Info("Connecting to database")
+
+		conn, err := clickhouse_wrapper.ConnectToClickhouse(clickhouseAddress, clickhouseUsername, clickhousePassword)
+
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error connecting to Clickhouse: %s", err.Error()))
+			os.Exit(1)
+		}
+
+		defer conn.Close()
+
+		migrationDatabase := viper.GetString("migrations-database")
+		migrationTable := viper.GetString("migrations-table")
+		migrationTableStoragePolicy := viper.GetString("migrations-table-storage-policy")
+
+		if migrationTableStoragePolicy == "" {
+			slog.Error("No storage policy provided for migration table")
+			os.Exit(1)
+		}
+
+		slog.Info(fmt.Sprintf("Reading %s history from %s.%s", source, sourceDatabase, sourceTable))
+
+		history, err := migrations.ImportHistory(conn, source, sourceDatabase, sourceTable, loadedMigrations)
+
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error reading migration history: %s", err.Error()))
+			os.Exit(1)
+		}
+
+		if !viper.GetBool("import-history-dry-run") {
+			slog.Info("Setting up migration table")
+
+			err = migrations.SetupMigrationTable(conn, migrationDatabase, migrationTable, migrationTableStoragePolicy)
+
+			if err != nil {
+				slog.Error(fmt.Sprintf("Error setting up migration table: %s", err.Error()))
+				os.Exit(1)
+			}
+		}
+
+		imported := 0
+
+		for _, migration := range history.Matched {
+			checksum, err := migration.ComputeChecksum()
+
+			if err != nil {
+				slog.Error(fmt.Sprintf("Error computing checksum of migration %s: %s", migration.Path, err.Error()))
+				os.Exit(1)
+			}
+
+			if viper.GetBool("import-history-dry-run") {
+				fmt.Printf("Would import migration %s (checksum %s)\n", migration.Path, checksum)
+				continue
+			}
+
+			isMigrationApplied, err := migration.CheckIfMigrationIsApplied(conn, migrationDatabase, migrationTable)
+
+			if err != nil {
+				slog.Error(fmt.Sprintf("Error checking if migration %s is applied: %s", migration.Path, err.Error()))
+				os.Exit(1)
+			}
+
+			if isMigrationApplied {
+				fmt.Printf("Migration %s is already recorded\n", migration.Path)
+				continue
+			}
+
+			err = migration.StoreMigration(conn, migrationDatabase, migrationTable)
+
+			if err != nil {
+				slog.Error(fmt.Sprintf("Error storing migration %s: %s", migration.Path, err.Error()))
+				os.Exit(1)
+			}
+
+			fmt.Printf("Imported migration %s (checksum %s)\n", migration.Path, checksum)
+			imported++
+		}
+
+		for _, version := range history.Unmatched {
+			fmt.Printf("Version %s applied by %s has no matching migration file\n", version, source)
+		}
+
+		slog.Info("Migration history imported", "matched", len(history.Matched), "imported", imported, "unmatched", len(history.Unmatched))
+	},
+}
+
+func init() {
+	migrationsCmd.AddCommand(importHistoryCmd)
+
+	importHistoryCmd.Flags().StringVar(&ImportHistoryFrom, "from", "", "Migration tool to import the history from: golang-migrate, goose or clickhouse-migrations")
+	viper.BindPFlag("import-history-from", importHistoryCmd.Flags().Lookup("from"))
+
+	importHistoryCmd.Flags().StringVar(&ImportHistoryFromDatabase, "from-database", "default", "ClickHouse database of the other tool's version table")
+	viper.BindPFlag("import-history-from-database", importHistoryCmd.Flags().Lookup("from-database"))
+
+	importHistoryCmd.Flags().StringVar(&ImportHistoryFromTable, "from-table", "", "ClickHouse table of the other tool's versions (defaults to the tool's own default)")
+	viper.BindPFlag("import-history-from-table", importHistoryCmd.Flags().Lookup("from-table"))
+
+	importHistoryCmd.Flags().BoolVar(&ImportHistoryDryRun, "dry-run", false, "Only report the migrations that would be imported")
+	viper.BindPFlag("import-history-dry-run", importHistoryCmd.Flags().Lookup("dry-run"))
+}
@@ -4,6 +4,7 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/migrations"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -13,6 +14,8 @@ var MigrationsTable string
 var MigrationsTableStoragePolicy string
 var MigrationsDirectory string
 var MigrationsIdentifier string
+var MigrationsRecursive bool
+var MigrationsFormat string
 
 // migrationsCmd represents the migrations command
 var migrationsCmd = &cobra.Command{
@@ -43,4 +46,12 @@ func init() {
 	migrationsCmd.PersistentFlags().StringVar(&MigrationsIdentifier, "migrations-identifier", "", "Identifier to caracterize the migrations (optional)")
 	viper.BindPFlag("migrations-identifier", migrationsCmd.PersistentFlags().Lookup("migrations-identifier"))
 	viper.BindEnv("migrations-identifier", "CLICKHOUSE_MIGRATIONS_IDENTIFIER")
+
+	migrationsCmd.PersistentFlags().BoolVar(&MigrationsRecursive, "migrations-recursive", false, "Load migrations from subdirectories of the migrations directory")
+	viper.BindPFlag("migrations-recursive", migrationsCmd.PersistentFlags().Lookup("migrations-recursive"))
+	viper.BindEnv("migrations-recursive", "CLICKHOUSE_MIGRATIONS_RECURSIVE")
+
+	migrationsCmd.PersistentFlags().StringVar(&MigrationsFormat, "migrations-format", migrations.DefaultMigrationFormat, "Layout of the migration files: toolbox, golang-migrate, goose, dbmate or clickhouse-migrations")
+	viper.BindPFlag("migrations-format", migrationsCmd.PersistentFlags().Lookup("migrations-format"))
+	viper.BindEnv("migrations-format", "CLICKHOUSE_MIGRATIONS_FORMAT")
 }
@@ -0,0 +1,111 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/clickhouse_wrapper"
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/dictionary_sync"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var DictionariesManifest string
+var DictionariesParallelism int
+
+// syncDictionariesCmd represents the syncDictionaries command
+var syncDictionariesCmd = &cobra.Command{
+	Use:   "syncDictionaries",
+	Short: "Synchronize every dictionary listed in a manifest into Clickhouse",
+	Long: `Synchronize every dictionary listed in a manifest into Clickhouse.
+
+The manifest is a YAML file listing the dictionaries to synchronize, with the
+same options as the syncDictionary flags. Options given under defaults apply
+to every dictionary, and relative sources are resolved against the directory
+of the manifest:
+
+  defaults:
+    atomic: true
+  dictionaries:
+    - dictionary-database: default
+      dictionary-name: countries
+      source: countries.csv
+    - dictionary-database: default
+      dictionary-name: currencies
+      source: currencies.ndjson
+      incremental: true
+      atomic: false`,
+	Run: func(cmd *cobra.Command, args []string) {
+		manifest := viper.GetString("dictionaries-manifest")
+
+		if manifest == "" {
+			cmd.Help()
+			slog.Error("No manifest provided")
+			os.Exit(1)
+		}
+
+		dictionaries, err := dictionary_sync.LoadManifest(manifest, dictionary_sync.DefaultOptions)
+
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error loading manifest: %s", err.Error()))
+			os.Exit(1)
+		}
+
+		clickhouseAddress := viper.GetString("clickhouse-address")
+		clickhouseUsername := viper.GetString("clickhouse-username")
+		clickhousePassword := viper.GetString("clickhouse-password")
+
+		conn, err := clickhouse_wrapper.ConnectToClickhouse(clickhouseAddress, clickhouseUsername, clickhousePassword)
+
+		if err != nil {
+			slog.Error(fmt.Sprintf("Error connecting to Clickhouse: %s", err.Error()))
+			os.Exit(1)
+		}
+
+		defer conn.Close()
+
+		slog.Info(fmt.Sprintf("Synchronizing %d dictionaries", len(dictionaries)))
+
+		connect := func(address string) (driver.Conn, error) {
+			return clickhouse_wrapper.ConnectToClickhouse(address, clickhouseUsername, clickhousePassword)
+		}
+
+		results := dictionary_sync.SynchronizeAll(conn, connect, dictionaries, viper.GetInt("dictionaries-parallelism"))
+		failed := 0
+
+		for _, result := range results {
+			if result.Err != nil {
+				fmt.Printf("FAILED  %s: %d rows in %s: %s\n", result.Options.Dictionary(), result.Rows, result.Duration, result.Err)
+				failed++
+				continue
+			}
+
+			fmt.Printf("OK      %s: %d rows in %s\n", result.Options.Dictionary(), result.Rows, result.Duration)
+		}
+
+		if failed > 0 {
+			slog.Error(fmt.Sprintf("%d of %d dictionaries failed to synchronize", failed, len(results)))
+			conn.Close()
+			os.Exit(1)
+		}
+
+		slog.Info("Dictionaries synchronized")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncDictionariesCmd)
+
+	syncDictionariesCmd.Flags().StringVar(&DictionariesManifest, "manifest", "", "YAML manifest listing the dictionaries to synchronize")
+	viper.BindPFlag("dictionaries-manifest", syncDictionariesCmd.Flags().Lookup("manifest"))
+	viper.BindEnv("dictionaries-manifest", "CLICKHOUSE_DICTIONARIES_MANIFEST")
+
+	syncDictionariesCmd.Flags().IntVar(&DictionariesParallelism, "parallelism", 4, "Maximum number of dictionaries synchronized at once")
+	viper.BindPFlag("dictionaries-parallelism", syncDictionariesCmd.Flags().Lookup("parallelism"))
+	viper.BindEnv("dictionaries-parallelism", "CLICKHOUSE_DICTIONARIES_PARALLELISM")
+}
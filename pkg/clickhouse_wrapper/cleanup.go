@@ -0,0 +1,152 @@
+package clickhouse_wrapper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// Strategies removing the previous data of a source table.
+const (
+	// Pick a strategy from the table engine and the server version.
+	CleanupAuto = "auto"
+	// `TRUNCATE TABLE`, supported by every engine storing data.
+	CleanupTruncate = "truncate"
+	// `DELETE FROM`, a lightweight delete waiting for the mutation.
+	CleanupLightweightDelete = "lightweight-delete"
+	// `ALTER TABLE ... DELETE`, a mutation rewriting the parts, waiting for it
+	// on every replica.
+	CleanupAlterDelete = "alter-delete"
+	// Load the data into a staging table, then replace the partitions of the
+	// table with its partitions.
+	CleanupPartitionReplace = "partition-replace"
+	// Load the data into a staging table, then exchange it with the table.
+	CleanupExchange = "exchange"
+)
+
+var CleanupStrategies = []string{
+	CleanupAuto,
+	CleanupTruncate,
+	CleanupLightweightDelete,
+	CleanupAlterDelete,
+	CleanupPartitionReplace,
+	CleanupExchange,
+}
+
+func ValidateCleanupStrategy(strategy string) error {
+	for _, candidate := range CleanupStrategies {
+		if strategy == candidate {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown cleanup strategy '%s': expected one of %s", strategy, strings.Join(CleanupStrategies, ", "))
+}
+
+// Cleanup strategy used for a table of the engine on a server of the version.
+// Lightweight deletes are only generally available from 23.3, older servers
+// and engines outside of the MergeTree family truncate the table instead of
+// running a heavy mutation.
+func ChooseCleanupStrategy(engine string, version driver.ServerVersion) string {
+	if !strings.HasSuffix(engine, "MergeTree") {
+		return CleanupTruncate
+	}
+
+	major, minor := version.Version.Major, version.Version.Minor
+
+	if major > 23 || (major == 23 && minor >= 3) {
+		return CleanupLightweightDelete
+	}
+
+	return CleanupTruncate
+}
+
+// Strategy picked by CleanupAuto for the table.
+func DefaultCleanupStrategy(conn driver.Conn, table string) (string, error) {
+	engine, err := GetTableEngine(conn, table)
+
+	if err != nil {
+		return "", err
+	}
+
+	version, err := conn.ServerVersion()
+
+	if err != nil {
+		return "", fmt.Errorf("cannot get server version: %s", err)
+	}
+
+	return ChooseCleanupStrategy(engine, *version), nil
+}
+
+// Remove every row of the table with the truncate, lightweight-delete or
+// alter-delete strategy. Deletes wait for their mutation to be done on every
+// replica, so that the data inserted next is not deleted.
+func CleanupTable(conn driver.Conn, sourceTable, strategy string) error {
+	var (
+		ctx = clickhouse.Context(context.Background(), clickhouse.WithSettings(clickhouse.Settings{
+			"mutations_sync":           2,
+			"lightweight_deletes_sync": 2,
+		}))
+	)
+
+	switch strategy {
+	case CleanupTruncate:
+		return conn.Exec(ctx, fmt.Sprintf("TRUNCATE TABLE %s", sourceTable))
+	case CleanupLightweightDelete:
+		return conn.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE 1 == 1", sourceTable))
+	case CleanupAlterDelete:
+		return conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s DELETE WHERE 1 == 1", sourceTable))
+	default:
+		return fmt.Errorf("cleanup strategy '%s' does not remove rows in place", strategy)
+	}
+}
+
+// Identifiers of the partitions holding active parts of the table.
+func GetTablePartitions(conn driver.Conn, table string) ([]string, error) {
+	var (
+		ctx = context.Background()
+	)
+
+	database, name := SplitTableName(table)
+
+	rows, err := conn.Query(ctx, "SELECT DISTINCT partition_id FROM system.parts WHERE database = if(? = '', currentDatabase(), ?) AND table = ? AND active ORDER BY partition_id", database, database, name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	partitions := make([]string, 0)
+
+	for rows.Next() {
+		var partition string
+
+		if err := rows.Scan(&partition); err != nil {
+			return nil, err
+		}
+
+		partitions = append(partitions, partition)
+	}
+
+	return partitions, rows.Err()
+}
+
+func ReplacePartition(conn driver.Conn, table, sourceTable, partition string) error {
+	var (
+		ctx = context.Background()
+	)
+
+	return conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s REPLACE PARTITION ID ? FROM %s", table, sourceTable), partition)
+}
+
+func DropPartition(conn driver.Conn, table, partition string) error {
+	var (
+		ctx = context.Background()
+	)
+
+	return conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s DROP PARTITION ID ?", table), partition)
+}
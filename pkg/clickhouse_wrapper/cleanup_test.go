@@ -0,0 +1,46 @@
+package clickhouse_wrapper
+
+import (
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
+)
+
+func TestChooseCleanupStrategy(t *testing.T) {
+	serverVersion := func(major, minor uint64) driver.ServerVersion {
+		return driver.ServerVersion{Version: proto.Version{Major: major, Minor: minor}}
+	}
+
+	tests := []struct {
+		engine   string
+		version  driver.ServerVersion
+		expected string
+	}{
+		{"MergeTree", serverVersion(24, 8), CleanupLightweightDelete},
+		{"ReplicatedReplacingMergeTree", serverVersion(23, 3), CleanupLightweightDelete},
+		{"SharedMergeTree", serverVersion(24, 1), CleanupLightweightDelete},
+		{"MergeTree", serverVersion(23, 2), CleanupTruncate},
+		{"ReplicatedMergeTree", serverVersion(22, 12), CleanupTruncate},
+		{"Memory", serverVersion(24, 8), CleanupTruncate},
+		{"Log", serverVersion(22, 8), CleanupTruncate},
+	}
+
+	for _, test := range tests {
+		if strategy := ChooseCleanupStrategy(test.engine, test.version); strategy != test.expected {
+			t.Errorf("ChooseCleanupStrategy(%s, %d.%d) = %s, expected %s", test.engine, test.version.Version.Major, test.version.Version.Minor, strategy, test.expected)
+		}
+	}
+}
+
+func TestValidateCleanupStrategy(t *testing.T) {
+	for _, strategy := range CleanupStrategies {
+		if err := ValidateCleanupStrategy(strategy); err != nil {
+			t.Errorf("ValidateCleanupStrategy(%s): %s", strategy, err)
+		}
+	}
+
+	if err := ValidateCleanupStrategy("drop"); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+}
@@ -4,40 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"reflect"
 	"strings"
+	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 )
 
-func GetDictionarySourceTable(conn driver.Conn, dictionaryDatabase, dictionaryName string) (string, error) {
-	var (
-		ctx = context.Background()
-	)
-
-	row := conn.QueryRow(ctx, "SELECT source FROM system.dictionaries WHERE database = ? AND name = ?", dictionaryDatabase, dictionaryName)
-
-	var source string
-
-	if err := row.Scan(&source); err != nil {
-		return "", err
-	}
-
-	if !strings.Contains(source, "ClickHouse: ") {
-		return "", fmt.Errorf("dictionary source is not a ClickHouse table: Source is '%s'", source)
-	}
-
-	return strings.Replace(source, "ClickHouse: ", "", 1), nil
-}
-
-func CleanupTable(conn driver.Conn, sourceTable string) error {
-	var (
-		ctx = context.Background()
-	)
-
-	return conn.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE 1 == 1", sourceTable))
-}
-
 func ReloadDictionary(conn driver.Conn, dictionaryDatabase, dictionaryName string) error {
 	var (
 		ctx = context.Background()
@@ -102,12 +76,14 @@ func ConnectToClickhouse(clickhouseAddress, clickhoustUsername, clickhousePasswo
 	return conn, nil
 }
 
-func BatchInsertDataInTable(conn driver.Conn, table string, rows [][]interface{}) error {
+// Insert rows in a table, the values of each row being those of the given
+// columns, in the same order.
+func BatchInsertDataInTable(conn driver.Conn, table string, columns []string, rows [][]interface{}) error {
 	var (
 		ctx = context.Background()
 	)
 
-	batch, err := conn.PrepareBatch(ctx, fmt.Sprintf("INSERT INTO %s", table))
+	batch, err := conn.PrepareBatch(ctx, fmt.Sprintf("INSERT INTO %s (%s)", table, quoteIdentifiers(columns)))
 
 	if err != nil {
 		return err
@@ -123,3 +99,447 @@ func BatchInsertDataInTable(conn driver.Conn, table string, rows [][]interface{}
 
 	return batch.Send()
 }
+
+type TableColumn struct {
+	Name string
+	Type string
+	// Kind of default expression of the column: DEFAULT, MATERIALIZED, ALIAS,
+	// EPHEMERAL, or empty when the column has none.
+	DefaultKind string
+}
+
+// Columns of the given table, in the order of the table definition.
+func GetTableColumns(conn driver.Conn, table string) ([]TableColumn, error) {
+	var (
+		ctx = context.Background()
+	)
+
+	database, name := SplitTableName(table)
+
+	rows, err := conn.Query(
+		ctx,
+		"SELECT name, type, default_kind FROM system.columns WHERE database = if(? = '', currentDatabase(), ?) AND table = ? ORDER BY position",
+		database,
+		database,
+		name,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	columns := make([]TableColumn, 0)
+
+	for rows.Next() {
+		var column TableColumn
+
+		if err := rows.Scan(&column.Name, &column.Type, &column.DefaultKind); err != nil {
+			return nil, err
+		}
+
+		columns = append(columns, column)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s does not exist or has no columns", table)
+	}
+
+	return columns, nil
+}
+
+// Timezone of the server, in which ClickHouse reads the values of time
+// columns without a timezone.
+func GetServerTimezone(conn driver.Conn) (*time.Location, error) {
+	version, err := conn.ServerVersion()
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot get server version: %s", err)
+	}
+
+	if version.Timezone == nil {
+		return time.UTC, nil
+	}
+
+	return version.Timezone, nil
+}
+
+// Compare the columns of the rows to insert with the columns of a table.
+// Missing columns are the table columns which are neither inserted nor
+// nullable and have no default expression, which ClickHouse fills with the
+// default value of their type. Extra columns are the inserted ones which do not
+// exist in the table or cannot be inserted (MATERIALIZED and ALIAS columns).
+func CompareInsertColumns(tableColumns []TableColumn, columns []string) ([]string, []string) {
+	inserted := make(map[string]bool)
+
+	for _, column := range columns {
+		inserted[column] = true
+	}
+
+	insertable := make(map[string]bool)
+	missing := make([]string, 0)
+
+	for _, column := range tableColumns {
+		switch column.DefaultKind {
+		case "":
+			insertable[column.Name] = true
+
+			if !inserted[column.Name] && !isNullableType(column.Type) {
+				missing = append(missing, column.Name)
+			}
+		case "DEFAULT", "EPHEMERAL":
+			insertable[column.Name] = true
+		}
+	}
+
+	extra := make([]string, 0)
+
+	for _, column := range columns {
+		if !insertable[column] {
+			extra = append(extra, column)
+		}
+	}
+
+	return missing, extra
+}
+
+// Whether a column type is nullable, including low cardinality nullable types.
+func isNullableType(columnType string) bool {
+	if strings.HasPrefix(columnType, "LowCardinality(") {
+		columnType = strings.TrimPrefix(columnType, "LowCardinality(")
+	}
+
+	return strings.HasPrefix(columnType, "Nullable(")
+}
+
+// Check that rows of the given columns can be inserted in a table, naming the
+// extra columns otherwise. Returns the missing columns, which are filled with
+// the default value of their type. Optional columns are not reported as
+// missing.
+func ValidateInsertColumns(conn driver.Conn, table string, columns []string, optionalColumns ...string) ([]string, error) {
+	tableColumns, err := GetTableColumns(conn, table)
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot read columns of %s: %s", table, err)
+	}
+
+	missing, extra := CompareInsertColumns(tableColumns, append(columns[:len(columns):len(columns)], optionalColumns...))
+	extra = removeColumns(extra, optionalColumns)
+
+	if len(extra) > 0 {
+		return missing, fmt.Errorf("columns do not match %s: extra columns not insertable in the table: %s", table, strings.Join(extra, ", "))
+	}
+
+	return missing, nil
+}
+
+func removeColumns(columns, removed []string) []string {
+	kept := make([]string, 0, len(columns))
+
+	for _, column := range columns {
+		isRemoved := false
+
+		for _, removedColumn := range removed {
+			isRemoved = isRemoved || column == removedColumn
+		}
+
+		if !isRemoved {
+			kept = append(kept, column)
+		}
+	}
+
+	return kept
+}
+
+// Split a `database.table` name into its database and table parts, removing
+// the quotes of quoted identifiers. The database is empty when the name is not
+// qualified.
+func SplitTableName(table string) (string, string) {
+	parser := &sourceParser{input: table}
+	parts := make([]string, 0, 2)
+
+	for parser.position < len(parser.input) {
+		var part string
+
+		switch c := parser.input[parser.position]; c {
+		case '`', '"':
+			part, _ = parser.quoted(c)
+		default:
+			start := parser.position
+
+			for parser.position < len(parser.input) && parser.input[parser.position] != '.' {
+				parser.position++
+			}
+
+			part = parser.input[start:parser.position]
+		}
+
+		parts = append(parts, part)
+
+		if len(parts) == 1 && parser.position < len(parser.input) && parser.input[parser.position] == '.' {
+			parser.position++
+			continue
+		}
+
+		break
+	}
+
+	if len(parts) == 1 {
+		return "", parts[0]
+	}
+
+	return parts[0], parts[1]
+}
+
+// Quoted name of a table, qualified by its database unless it is empty.
+func QualifiedTableName(database, table string) string {
+	if database == "" {
+		return QuoteIdentifier(table)
+	}
+
+	return fmt.Sprintf("%s.%s", QuoteIdentifier(database), QuoteIdentifier(table))
+}
+
+func GetDatabaseEngine(conn driver.Conn, database string) (string, error) {
+	var (
+		ctx = context.Background()
+	)
+
+	row := conn.QueryRow(ctx, "SELECT engine FROM system.databases WHERE name = ?", database)
+
+	var engine string
+
+	if err := row.Scan(&engine); err != nil {
+		return "", err
+	}
+
+	return engine, nil
+}
+
+func CreateTableAs(conn driver.Conn, table, sourceTable string) error {
+	var (
+		ctx = context.Background()
+	)
+
+	return conn.Exec(ctx, fmt.Sprintf("CREATE TABLE %s AS %s", table, sourceTable))
+}
+
+// Insert every row of a table into another table of the same structure.
+func CopyTableData(conn driver.Conn, table, sourceTable string) error {
+	var (
+		ctx = context.Background()
+	)
+
+	return conn.Exec(ctx, fmt.Sprintf("INSERT INTO %s SELECT * FROM %s", table, sourceTable))
+}
+
+// Names of the tables of a database starting with the given prefix, sorted by
+// name.
+func ListTables(conn driver.Conn, database, prefix string) ([]string, error) {
+	var (
+		ctx = context.Background()
+	)
+
+	rows, err := conn.Query(ctx, "SELECT name FROM system.tables WHERE database = ? AND startsWith(name, ?) ORDER BY name", database, prefix)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tables := make([]string, 0)
+
+	for rows.Next() {
+		var table string
+
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+
+		tables = append(tables, table)
+	}
+
+	return tables, rows.Err()
+}
+
+func DropTable(conn driver.Conn, table string) error {
+	var (
+		ctx = context.Background()
+	)
+
+	return conn.Exec(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", table))
+}
+
+func CountRows(conn driver.Conn, table string) (uint64, error) {
+	return CountRowsWhere(conn, table, "")
+}
+
+// Count the rows of a table matching the given filter, or all of them when it
+// is empty.
+func CountRowsWhere(conn driver.Conn, table, where string) (uint64, error) {
+	var (
+		ctx = context.Background()
+	)
+
+	query := fmt.Sprintf("SELECT count() FROM %s", table)
+
+	if where != "" {
+		query = fmt.Sprintf("%s WHERE %s", query, where)
+	}
+
+	row := conn.QueryRow(ctx, query)
+
+	var count uint64
+
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Swap the contents of two tables of the same database. Atomic databases
+// support `EXCHANGE TABLES`, other engines fall back to a three-way `RENAME`.
+func SwapTables(conn driver.Conn, table, otherTable string) error {
+	var (
+		ctx = context.Background()
+	)
+
+	database, name := SplitTableName(table)
+
+	if database == "" {
+		row := conn.QueryRow(ctx, "SELECT currentDatabase()")
+
+		if err := row.Scan(&database); err != nil {
+			return err
+		}
+	}
+
+	engine, err := GetDatabaseEngine(conn, database)
+
+	if err != nil {
+		return err
+	}
+
+	if engine == "Atomic" || engine == "Replicated" {
+		return conn.Exec(ctx, fmt.Sprintf("EXCHANGE TABLES %s AND %s", table, otherTable))
+	}
+
+	temporaryTable := QualifiedTableName(database, name+"_swap")
+
+	return conn.Exec(ctx, fmt.Sprintf("RENAME TABLE %s TO %s, %s TO %s, %s TO %s", table, temporaryTable, otherTable, table, temporaryTable, otherTable))
+}
+
+type DictionaryStatus struct {
+	Status        string
+	ElementCount  uint64
+	LastException string
+}
+
+func GetDictionaryStatus(conn driver.Conn, dictionaryDatabase, dictionaryName string) (*DictionaryStatus, error) {
+	var (
+		ctx = context.Background()
+	)
+
+	row := conn.QueryRow(
+		ctx,
+		"SELECT toString(status), element_count, last_exception FROM system.dictionaries WHERE database = ? AND name = ?",
+		dictionaryDatabase,
+		dictionaryName,
+	)
+
+	var status DictionaryStatus
+
+	if err := row.Scan(&status.Status, &status.ElementCount, &status.LastException); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// Poll the status of the dictionary until it is done loading, and check that
+// it loaded without exception.
+func WaitForDictionaryLoaded(conn driver.Conn, dictionaryDatabase, dictionaryName string, timeout time.Duration) (*DictionaryStatus, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		status, err := GetDictionaryStatus(conn, dictionaryDatabase, dictionaryName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch status.Status {
+		case "LOADING", "LOADED_AND_RELOADING", "NOT_LOADED":
+			if time.Now().After(deadline) {
+				return status, fmt.Errorf("dictionary is still %s after %s", status.Status, timeout)
+			}
+
+			time.Sleep(500 * time.Millisecond)
+		case "LOADED":
+			if status.LastException != "" {
+				return status, fmt.Errorf("dictionary loaded with exception: %s", status.LastException)
+			}
+
+			return status, nil
+		default:
+			if status.LastException != "" {
+				return status, fmt.Errorf("dictionary is %s: %s", status.Status, status.LastException)
+			}
+
+			return status, fmt.Errorf("dictionary is %s", status.Status)
+		}
+	}
+}
+
+// Run a query and call onRow with the values of each row, scanned into the Go
+// types of the columns. onColumns is called with the names and types of the
+// columns before the first row.
+func StreamQuery(conn driver.Conn, query string, onColumns func(columns []TableColumn) error, onRow func(values []interface{}) error) error {
+	var (
+		ctx = context.Background()
+	)
+
+	rows, err := conn.Query(ctx, query)
+
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	columnTypes := rows.ColumnTypes()
+	columns := make([]TableColumn, len(columnTypes))
+
+	for i, columnType := range columnTypes {
+		columns[i] = TableColumn{Name: columnType.Name(), Type: columnType.DatabaseTypeName()}
+	}
+
+	if err := onColumns(columns); err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		values := make([]interface{}, len(columnTypes))
+
+		for i, columnType := range columnTypes {
+			values[i] = reflect.New(columnType.ScanType()).Interface()
+		}
+
+		if err := rows.Scan(values...); err != nil {
+			return err
+		}
+
+		if err := onRow(values); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
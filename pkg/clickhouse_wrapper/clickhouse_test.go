@@ -0,0 +1,27 @@
+package clickhouse_wrapper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareInsertColumns(t *testing.T) {
+	tableColumns := []TableColumn{
+		{Name: "id", Type: "UInt64"},
+		{Name: "name", Type: "String"},
+		{Name: "label", Type: "LowCardinality(Nullable(String))"},
+		{Name: "note", Type: "Nullable(String)"},
+		{Name: "updated_at", Type: "DateTime", DefaultKind: "DEFAULT"},
+		{Name: "name_length", Type: "UInt64", DefaultKind: "MATERIALIZED"},
+	}
+
+	missing, extra := CompareInsertColumns(tableColumns, []string{"id", "name_length", "unknown"})
+
+	if !reflect.DeepEqual(missing, []string{"name"}) {
+		t.Errorf("missing = %v, expected [name]", missing)
+	}
+
+	if !reflect.DeepEqual(extra, []string{"name_length", "unknown"}) {
+		t.Errorf("extra = %v, expected [name_length unknown]", extra)
+	}
+}
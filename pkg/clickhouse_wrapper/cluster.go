@@ -0,0 +1,186 @@
+package clickhouse_wrapper
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// Replica of a cluster, as listed in `system.clusters`.
+type ClusterHost struct {
+	ShardNum   uint32
+	ReplicaNum uint32
+	HostName   string
+	Port       uint16
+}
+
+// Address of the native protocol of the host, as `host:port`.
+func (h ClusterHost) Address() string {
+	return net.JoinHostPort(h.HostName, strconv.Itoa(int(h.Port)))
+}
+
+func GetClusterHosts(conn driver.Conn, cluster string) ([]ClusterHost, error) {
+	var (
+		ctx = context.Background()
+	)
+
+	rows, err := conn.Query(ctx, "SELECT shard_num, replica_num, host_name, port FROM system.clusters WHERE cluster = ? ORDER BY shard_num, replica_num", cluster)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	hosts := make([]ClusterHost, 0)
+
+	for rows.Next() {
+		var host ClusterHost
+
+		if err := rows.Scan(&host.ShardNum, &host.ReplicaNum, &host.HostName, &host.Port); err != nil {
+			return nil, err
+		}
+
+		hosts = append(hosts, host)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("cluster %s does not exist or has no host", cluster)
+	}
+
+	return hosts, nil
+}
+
+func ReloadDictionaryOnCluster(conn driver.Conn, cluster, dictionaryDatabase, dictionaryName string) error {
+	var (
+		ctx = context.Background()
+	)
+
+	return conn.Exec(ctx, fmt.Sprintf("SYSTEM RELOAD DICTIONARY ON CLUSTER %s %s", QuoteIdentifier(cluster), QualifiedTableName(dictionaryDatabase, dictionaryName)))
+}
+
+// Wait on every replica of the cluster for a replicated table to fetch the
+// data written on the other replicas of its shard.
+func SyncReplicaOnCluster(conn driver.Conn, cluster, table string) error {
+	var (
+		ctx = context.Background()
+	)
+
+	return conn.Exec(ctx, fmt.Sprintf("SYSTEM SYNC REPLICA ON CLUSTER %s %s", QuoteIdentifier(cluster), table))
+}
+
+func GetTableEngine(conn driver.Conn, table string) (string, error) {
+	var (
+		ctx = context.Background()
+	)
+
+	database, name := SplitTableName(table)
+
+	row := conn.QueryRow(ctx, "SELECT engine FROM system.tables WHERE database = if(? = '', currentDatabase(), ?) AND name = ?", database, database, name)
+
+	var engine string
+
+	if err := row.Scan(&engine); err != nil {
+		return "", fmt.Errorf("cannot find table %s: %s", table, err)
+	}
+
+	return engine, nil
+}
+
+// Whether the data of a table engine is shared by the replicas of a cluster,
+// so that writing on one host is enough.
+func IsReplicatedEngine(engine string) bool {
+	return strings.HasPrefix(engine, "Replicated") || strings.HasPrefix(engine, "Shared")
+}
+
+// Status of a dictionary on one host of a cluster.
+type HostDictionaryStatus struct {
+	Host string
+	DictionaryStatus
+}
+
+func GetClusterDictionaryStatus(conn driver.Conn, cluster, dictionaryDatabase, dictionaryName string) ([]HostDictionaryStatus, error) {
+	var (
+		ctx = context.Background()
+	)
+
+	rows, err := conn.Query(
+		ctx,
+		"SELECT hostName(), toString(status), element_count, last_exception FROM clusterAllReplicas(?, system.dictionaries) WHERE database = ? AND name = ? ORDER BY hostName()",
+		cluster,
+		dictionaryDatabase,
+		dictionaryName,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	statuses := make([]HostDictionaryStatus, 0)
+
+	for rows.Next() {
+		var status HostDictionaryStatus
+
+		if err := rows.Scan(&status.Host, &status.Status, &status.ElementCount, &status.LastException); err != nil {
+			return nil, err
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, rows.Err()
+}
+
+// Poll the status of the dictionary on every host of the cluster until it is
+// done loading everywhere, and check that it loaded without exception.
+func WaitForClusterDictionaryLoaded(conn driver.Conn, cluster, dictionaryDatabase, dictionaryName string, timeout time.Duration) ([]HostDictionaryStatus, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		statuses, err := GetClusterDictionaryStatus(conn, cluster, dictionaryDatabase, dictionaryName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		loading := false
+
+		for _, status := range statuses {
+			switch status.Status {
+			case "LOADING", "LOADED_AND_RELOADING", "NOT_LOADED":
+				if time.Now().After(deadline) {
+					return statuses, fmt.Errorf("dictionary is still %s on %s after %s", status.Status, status.Host, timeout)
+				}
+
+				loading = true
+			case "LOADED":
+				if status.LastException != "" {
+					return statuses, fmt.Errorf("dictionary loaded with exception on %s: %s", status.Host, status.LastException)
+				}
+			default:
+				if status.LastException != "" {
+					return statuses, fmt.Errorf("dictionary is %s on %s: %s", status.Status, status.Host, status.LastException)
+				}
+
+				return statuses, fmt.Errorf("dictionary is %s on %s", status.Status, status.Host)
+			}
+		}
+
+		if !loading {
+			return statuses, nil
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}
+}
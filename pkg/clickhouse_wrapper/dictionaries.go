@@ -0,0 +1,145 @@
+package clickhouse_wrapper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// State of a dictionary, as reported by `system.dictionaries`.
+type DictionaryInfo struct {
+	Database string
+	Name     string
+	Status   string
+	Origin   string
+	// Layout of the dictionary, such as Hashed or ComplexKeyHashed.
+	Type           string
+	KeyNames       []string
+	KeyTypes       []string
+	AttributeNames []string
+	AttributeTypes []string
+	Source         string
+
+	ElementCount   uint64
+	BytesAllocated uint64
+	QueryCount     uint64
+	HitRate        float64
+	LoadFactor     float64
+	LifetimeMin    uint64
+	LifetimeMax    uint64
+
+	LoadingStartTime         time.Time
+	LastSuccessfulUpdateTime time.Time
+	LoadingDuration          time.Duration
+	LastException            string
+}
+
+const dictionaryInfoQuery = `
+SELECT
+	database,
+	name,
+	toString(status),
+	origin,
+	type,
+	key.names,
+	key.types,
+	attribute.names,
+	attribute.types,
+	source,
+	toUInt64(element_count),
+	toUInt64(bytes_allocated),
+	toUInt64(query_count),
+	toFloat64(hit_rate),
+	toFloat64(load_factor),
+	toUInt64(lifetime_min),
+	toUInt64(lifetime_max),
+	loading_start_time,
+	last_successful_update_time,
+	toFloat64(loading_duration),
+	last_exception
+FROM system.dictionaries`
+
+func scanDictionaryInfo(scan func(dest ...interface{}) error) (*DictionaryInfo, error) {
+	var info DictionaryInfo
+	var loadingDuration float64
+
+	err := scan(
+		&info.Database,
+		&info.Name,
+		&info.Status,
+		&info.Origin,
+		&info.Type,
+		&info.KeyNames,
+		&info.KeyTypes,
+		&info.AttributeNames,
+		&info.AttributeTypes,
+		&info.Source,
+		&info.ElementCount,
+		&info.BytesAllocated,
+		&info.QueryCount,
+		&info.HitRate,
+		&info.LoadFactor,
+		&info.LifetimeMin,
+		&info.LifetimeMax,
+		&info.LoadingStartTime,
+		&info.LastSuccessfulUpdateTime,
+		&loadingDuration,
+		&info.LastException,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	info.LoadingDuration = time.Duration(loadingDuration * float64(time.Second))
+
+	return &info, nil
+}
+
+// Dictionaries of the given database, or of every database when it is empty,
+// sorted by database and name.
+func ListDictionaries(conn driver.Conn, database string) ([]DictionaryInfo, error) {
+	var (
+		ctx = context.Background()
+	)
+
+	rows, err := conn.Query(ctx, fmt.Sprintf("%s WHERE ? = '' OR database = ? ORDER BY database, name", dictionaryInfoQuery), database, database)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	dictionaries := make([]DictionaryInfo, 0)
+
+	for rows.Next() {
+		info, err := scanDictionaryInfo(rows.Scan)
+
+		if err != nil {
+			return nil, err
+		}
+
+		dictionaries = append(dictionaries, *info)
+	}
+
+	return dictionaries, rows.Err()
+}
+
+func GetDictionaryInfo(conn driver.Conn, dictionaryDatabase, dictionaryName string) (*DictionaryInfo, error) {
+	var (
+		ctx = context.Background()
+	)
+
+	row := conn.QueryRow(ctx, fmt.Sprintf("%s WHERE database = ? AND name = ?", dictionaryInfoQuery), dictionaryDatabase, dictionaryName)
+
+	info, err := scanDictionaryInfo(row.Scan)
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot find dictionary %s.%s: %s", dictionaryDatabase, dictionaryName, err)
+	}
+
+	return info, nil
+}
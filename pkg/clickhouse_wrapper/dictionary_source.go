@@ -0,0 +1,412 @@
+package clickhouse_wrapper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// Source of a dictionary, as declared in its `SOURCE(...)` clause.
+type DictionarySource struct {
+	// Type of the source, such as CLICKHOUSE, MYSQL or FILE.
+	Type string
+	// Host and port of a remote source, empty for the local server.
+	Host string
+	Port string
+	// Table of the source, the database defaulting to the dictionary one.
+	Database string
+	Table    string
+	// Query of sources reading a query rather than a table.
+	Query string
+	// Filter applied by the dictionary to the rows of its source table.
+	Where string
+	// Named collection holding the source configuration.
+	NamedCollection string
+
+	// Whether the host of the source is the server the dictionary lives on.
+	isLocal bool
+}
+
+// Quoted name of the source table, as `database`.`table`.
+func (s *DictionarySource) QualifiedTable() string {
+	return QualifiedTableName(s.Database, s.Table)
+}
+
+// Name of the local ClickHouse table which can be written to synchronize the
+// dictionary, or an error explaining why the source cannot be written.
+func (s *DictionarySource) WritableTable() (string, error) {
+	if s.Type != "CLICKHOUSE" {
+		return "", fmt.Errorf("dictionary source is a %s source, not a ClickHouse table", s.Type)
+	}
+
+	if s.Query != "" {
+		return "", fmt.Errorf("dictionary source is the query '%s', not a table", s.Query)
+	}
+
+	if s.Table == "" {
+		if s.NamedCollection != "" {
+			return "", fmt.Errorf("dictionary source table is defined in the named collection '%s', which cannot be read", s.NamedCollection)
+		}
+
+		return "", fmt.Errorf("dictionary source has no table")
+	}
+
+	if !s.isLocal {
+		return "", fmt.Errorf("dictionary source is the table %s on the remote server %s, not a local table", s.QualifiedTable(), strings.TrimSuffix(s.Host+":"+s.Port, ":"))
+	}
+
+	return s.QualifiedTable(), nil
+}
+
+// Resolve the source of a dictionary. The source is read from the dictionary
+// definition, and from the source description of `system.dictionaries` for
+// dictionaries defined in the server configuration.
+func GetDictionarySource(conn driver.Conn, dictionaryDatabase, dictionaryName string) (*DictionarySource, error) {
+	var (
+		ctx = context.Background()
+	)
+
+	row := conn.QueryRow(ctx, "SELECT source FROM system.dictionaries WHERE database = ? AND name = ?", dictionaryDatabase, dictionaryName)
+
+	var description string
+
+	if err := row.Scan(&description); err != nil {
+		return nil, fmt.Errorf("cannot find dictionary %s.%s: %s", dictionaryDatabase, dictionaryName, err)
+	}
+
+	var source *DictionarySource
+
+	row = conn.QueryRow(ctx, fmt.Sprintf("SHOW CREATE DICTIONARY %s", QualifiedTableName(dictionaryDatabase, dictionaryName)))
+
+	var statement string
+
+	if err := row.Scan(&statement); err == nil {
+		parsed, err := ParseDictionarySource(statement)
+
+		if err != nil {
+			return nil, err
+		}
+
+		source = parsed
+	} else {
+		parsed, err := parseDictionarySourceDescription(description)
+
+		if err != nil {
+			return nil, err
+		}
+
+		source = parsed
+	}
+
+	if source.Type == "CLICKHOUSE" && source.Database == "" {
+		source.Database = dictionaryDatabase
+	}
+
+	isLocal, err := isLocalHost(conn, source.Host, source.Port)
+
+	if err != nil {
+		return nil, err
+	}
+
+	source.isLocal = isLocal
+
+	return source, nil
+}
+
+// Name of the local table which is the source of the dictionary.
+func GetDictionarySourceTable(conn driver.Conn, dictionaryDatabase, dictionaryName string) (string, error) {
+	source, err := GetDictionarySource(conn, dictionaryDatabase, dictionaryName)
+
+	if err != nil {
+		return "", err
+	}
+
+	return source.WritableTable()
+}
+
+// Whether the host and port of a ClickHouse source designate the connected
+// server.
+func isLocalHost(conn driver.Conn, host, port string) (bool, error) {
+	var (
+		ctx = context.Background()
+	)
+
+	row := conn.QueryRow(ctx, "SELECT hostName(), fqdn(), toString(tcpPort())")
+
+	var hostName, fqdn, tcpPort string
+
+	if err := row.Scan(&hostName, &fqdn, &tcpPort); err != nil {
+		return false, err
+	}
+
+	return isServerAddress(host, port, hostName, fqdn, tcpPort), nil
+}
+
+// Whether a host and port designate the server of the given host name, fully
+// qualified domain name and native protocol port. An empty port is the
+// server's one.
+func isServerAddress(host, port, hostName, fqdn, tcpPort string) bool {
+	if port != "" && port != tcpPort {
+		return false
+	}
+
+	switch strings.ToLower(host) {
+	case "", "localhost", "127.0.0.1", "::1":
+		return true
+	}
+
+	return strings.EqualFold(host, hostName) || strings.EqualFold(host, fqdn)
+}
+
+// Parse the `SOURCE(...)` clause of a `CREATE DICTIONARY` statement.
+func ParseDictionarySource(statement string) (*DictionarySource, error) {
+	clause, ok := findSourceClause(statement)
+
+	if !ok {
+		return nil, fmt.Errorf("cannot find the SOURCE clause of the dictionary definition")
+	}
+
+	parser := &sourceParser{input: clause}
+
+	sourceType := parser.word()
+
+	if sourceType == "" || !parser.consume('(') {
+		return nil, fmt.Errorf("invalid SOURCE clause: %s", clause)
+	}
+
+	arguments := make(map[string]string)
+
+	for {
+		parser.skipSpaces()
+
+		if parser.consume(')') {
+			break
+		}
+
+		key := parser.word()
+
+		if key == "" {
+			return nil, fmt.Errorf("invalid SOURCE clause: %s", clause)
+		}
+
+		value, err := parser.value()
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid SOURCE clause: %s", err)
+		}
+
+		arguments[strings.ToUpper(key)] = value
+	}
+
+	return &DictionarySource{
+		Type:            strings.ToUpper(sourceType),
+		Host:            arguments["HOST"],
+		Port:            arguments["PORT"],
+		Database:        arguments["DB"],
+		Table:           arguments["TABLE"],
+		Query:           arguments["QUERY"],
+		Where:           arguments["WHERE"],
+		NamedCollection: arguments["NAME"],
+	}, nil
+}
+
+// Parse the source description of `system.dictionaries`, such as
+// `ClickHouse: db.table`.
+func parseDictionarySourceDescription(description string) (*DictionarySource, error) {
+	table, found := strings.CutPrefix(description, "ClickHouse: ")
+
+	if !found {
+		sourceType, _, _ := strings.Cut(description, ":")
+
+		return &DictionarySource{Type: strings.ToUpper(sourceType)}, nil
+	}
+
+	table, where, _ := strings.Cut(table, ", where: ")
+	database, name := SplitTableName(table)
+
+	return &DictionarySource{
+		Type:     "CLICKHOUSE",
+		Database: database,
+		Table:    name,
+		Where:    where,
+	}, nil
+}
+
+// Content of the SOURCE clause of a statement, from the source type to its
+// closing parenthesis. Strings and quoted identifiers are skipped, so that a
+// column or a comment cannot be mistaken for the clause.
+func findSourceClause(statement string) (string, bool) {
+	parser := &sourceParser{input: statement}
+
+	for parser.position < len(parser.input) {
+		switch c := parser.input[parser.position]; {
+		case c == '\'' || c == '`' || c == '"':
+			parser.quoted(c)
+		case isWordCharacter(rune(c)):
+			if strings.EqualFold(parser.word(), "SOURCE") && parser.consume('(') {
+				start := parser.position
+
+				if !parser.skipParentheses() {
+					return "", false
+				}
+
+				return parser.input[start : parser.position-1], true
+			}
+		default:
+			parser.position++
+		}
+	}
+
+	return "", false
+}
+
+type sourceParser struct {
+	input    string
+	position int
+}
+
+func isWordCharacter(c rune) bool {
+	return c == '_' || c == '.' || c == '-' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+func (p *sourceParser) skipSpaces() {
+	for p.position < len(p.input) && unicode.IsSpace(rune(p.input[p.position])) {
+		p.position++
+	}
+}
+
+func (p *sourceParser) consume(c byte) bool {
+	p.skipSpaces()
+
+	if p.position < len(p.input) && p.input[p.position] == c {
+		p.position++
+		return true
+	}
+
+	return false
+}
+
+func (p *sourceParser) word() string {
+	p.skipSpaces()
+
+	start := p.position
+
+	for p.position < len(p.input) && isWordCharacter(rune(p.input[p.position])) {
+		p.position++
+	}
+
+	return p.input[start:p.position]
+}
+
+// Read a string or a quoted identifier starting at the current position, and
+// return its unescaped content.
+func (p *sourceParser) quoted(quote byte) (string, bool) {
+	var builder strings.Builder
+
+	p.position++
+
+	for p.position < len(p.input) {
+		c := p.input[p.position]
+		p.position++
+
+		switch {
+		case c == '\\' && p.position < len(p.input):
+			escaped := p.input[p.position]
+			p.position++
+
+			switch escaped {
+			case 'n':
+				builder.WriteByte('\n')
+			case 't':
+				builder.WriteByte('\t')
+			case 'r':
+				builder.WriteByte('\r')
+			case '0':
+				builder.WriteByte(0)
+			default:
+				builder.WriteByte(escaped)
+			}
+		case c == quote:
+			if p.position < len(p.input) && p.input[p.position] == quote {
+				builder.WriteByte(quote)
+				p.position++
+				continue
+			}
+
+			return builder.String(), true
+		default:
+			builder.WriteByte(c)
+		}
+	}
+
+	return builder.String(), false
+}
+
+// Move past the parenthesis closing the one just consumed.
+func (p *sourceParser) skipParentheses() bool {
+	depth := 1
+
+	for p.position < len(p.input) {
+		switch c := p.input[p.position]; c {
+		case '\'', '`', '"':
+			if _, ok := p.quoted(c); !ok {
+				return false
+			}
+
+			continue
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+
+		p.position++
+
+		if depth == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Read the value of a source argument: a string, a number or identifier, or a
+// parenthesized expression.
+func (p *sourceParser) value() (string, error) {
+	p.skipSpaces()
+
+	if p.position >= len(p.input) {
+		return "", fmt.Errorf("missing argument value")
+	}
+
+	switch c := p.input[p.position]; c {
+	case '\'', '`', '"':
+		value, ok := p.quoted(c)
+
+		if !ok {
+			return "", fmt.Errorf("unterminated string")
+		}
+
+		return value, nil
+	case '(':
+		p.position++
+		start := p.position
+
+		if !p.skipParentheses() {
+			return "", fmt.Errorf("unbalanced parentheses")
+		}
+
+		return p.input[start : p.position-1], nil
+	default:
+		value := p.word()
+
+		if value == "" {
+			return "", fmt.Errorf("unexpected character '%c'", c)
+		}
+
+		return value, nil
+	}
+}
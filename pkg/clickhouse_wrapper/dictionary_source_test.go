@@ -0,0 +1,44 @@
+package clickhouse_wrapper
+
+import "testing"
+
+func TestParseDictionarySource(t *testing.T) {
+	statement := "CREATE DICTIONARY default.countries (`id` UInt64, `name` String) PRIMARY KEY id " +
+		"SOURCE(CLICKHOUSE(HOST 'replica-1' PORT 9440 DB 'reference' TABLE 'countries' WHERE 'active = 1')) " +
+		"LIFETIME(MIN 0 MAX 300) LAYOUT(HASHED())"
+
+	source, err := ParseDictionarySource(statement)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if source.Type != "CLICKHOUSE" || source.Host != "replica-1" || source.Port != "9440" {
+		t.Errorf("parsed %s source on %s:%s", source.Type, source.Host, source.Port)
+	}
+
+	if source.Database != "reference" || source.Table != "countries" || source.Where != "active = 1" {
+		t.Errorf("parsed table %s.%s where %s", source.Database, source.Table, source.Where)
+	}
+}
+
+func TestIsServerAddress(t *testing.T) {
+	tests := []struct {
+		host     string
+		port     string
+		expected bool
+	}{
+		{"", "", true},
+		{"localhost", "9000", true},
+		{"127.0.0.1", "9001", false},
+		{"CLICKHOUSE-1", "", true},
+		{"clickhouse-1.example.com", "9000", true},
+		{"clickhouse-2", "9000", false},
+	}
+
+	for _, test := range tests {
+		if isLocal := isServerAddress(test.host, test.port, "clickhouse-1", "clickhouse-1.example.com", "9000"); isLocal != test.expected {
+			t.Errorf("isServerAddress(%q, %q) = %t, expected %t", test.host, test.port, isLocal, test.expected)
+		}
+	}
+}
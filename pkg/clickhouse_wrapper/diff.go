@@ -0,0 +1,248 @@
+package clickhouse_wrapper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// Quote an identifier with backquotes, so that any column name can be used in
+// generated queries.
+func QuoteIdentifier(identifier string) string {
+	return "`" + strings.ReplaceAll(strings.ReplaceAll(identifier, "\\", "\\\\"), "`", "\\`") + "`"
+}
+
+func quoteIdentifiers(identifiers []string) string {
+	quoted := make([]string, len(identifiers))
+
+	for i, identifier := range identifiers {
+		quoted[i] = QuoteIdentifier(identifier)
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+func GetDictionaryKeyNames(conn driver.Conn, dictionaryDatabase, dictionaryName string) ([]string, error) {
+	var (
+		ctx = context.Background()
+	)
+
+	row := conn.QueryRow(ctx, "SELECT key.names FROM system.dictionaries WHERE database = ? AND name = ?", dictionaryDatabase, dictionaryName)
+
+	var keyNames []string
+
+	if err := row.Scan(&keyNames); err != nil {
+		return nil, err
+	}
+
+	if len(keyNames) == 0 {
+		return nil, fmt.Errorf("dictionary %s.%s has no key", dictionaryDatabase, dictionaryName)
+	}
+
+	return keyNames, nil
+}
+
+// Difference between the rows of a table and the rows of its staging copy,
+// compared by key.
+type TableDiff struct {
+	Added   uint64
+	Updated uint64
+	Removed uint64
+}
+
+func (d TableDiff) IsEmpty() bool {
+	return d.Added == 0 && d.Updated == 0 && d.Removed == 0
+}
+
+// Queries comparing a table with its staging copy. Rows are compared through
+// a hash of the given columns, computed by ClickHouse so that values are
+// compared with their column types.
+type tableComparison struct {
+	table        string
+	stagingTable string
+	keys         string
+	columns      string
+}
+
+// With an is_deleted column, the table is a ReplacingMergeTree which is read
+// with FINAL, ignoring the rows flagged as deleted.
+func newTableComparison(table, stagingTable string, keys, columns []string, isDeletedColumn string) tableComparison {
+	if isDeletedColumn != "" {
+		table = fmt.Sprintf("(SELECT * FROM %s FINAL WHERE %s = 0)", table, QuoteIdentifier(isDeletedColumn))
+	}
+
+	return tableComparison{
+		table:        table,
+		stagingTable: stagingTable,
+		keys:         quoteIdentifiers(keys),
+		columns:      quoteIdentifiers(columns),
+	}
+}
+
+func (c tableComparison) addedKeys() string {
+	return fmt.Sprintf("SELECT %s FROM %s WHERE (%s) NOT IN (SELECT %s FROM %s)", c.keys, c.stagingTable, c.keys, c.keys, c.table)
+}
+
+func (c tableComparison) removedKeys() string {
+	return fmt.Sprintf("SELECT %s FROM %s WHERE (%s) NOT IN (SELECT %s FROM %s)", c.keys, c.table, c.keys, c.keys, c.stagingTable)
+}
+
+func (c tableComparison) updatedKeys() string {
+	return fmt.Sprintf(
+		"SELECT %s FROM (SELECT %s, cityHash64(%s) AS staging_hash FROM %s) INNER JOIN (SELECT %s, cityHash64(%s) AS source_hash FROM %s) USING (%s) WHERE staging_hash != source_hash",
+		c.keys,
+		c.keys, c.columns, c.stagingTable,
+		c.keys, c.columns, c.table,
+		c.keys,
+	)
+}
+
+func countRowsOf(conn driver.Conn, query string) (uint64, error) {
+	var (
+		ctx = context.Background()
+	)
+
+	row := conn.QueryRow(ctx, fmt.Sprintf("SELECT count() FROM (%s)", query))
+
+	var count uint64
+
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Compare a table with its staging copy.
+func DiffTables(conn driver.Conn, table, stagingTable string, keys, columns []string, isDeletedColumn string) (TableDiff, error) {
+	var diff TableDiff
+	var err error
+
+	comparison := newTableComparison(table, stagingTable, keys, columns, isDeletedColumn)
+
+	if diff.Added, err = countRowsOf(conn, comparison.addedKeys()); err != nil {
+		return diff, fmt.Errorf("cannot count added rows: %s", err)
+	}
+
+	if diff.Updated, err = countRowsOf(conn, comparison.updatedKeys()); err != nil {
+		return diff, fmt.Errorf("cannot count updated rows: %s", err)
+	}
+
+	if diff.Removed, err = countRowsOf(conn, comparison.removedKeys()); err != nil {
+		return diff, fmt.Errorf("cannot count removed rows: %s", err)
+	}
+
+	return diff, nil
+}
+
+// Statement of the application of a table diff, and what it does.
+type diffStatement struct {
+	action string
+	query  string
+}
+
+// Statements applying the difference between a table and its staging copy to
+// the table, in order. Removed and updated rows are deleted before added and
+// updated rows are inserted. When an is_deleted column is given, the table is
+// expected to be a ReplacingMergeTree: nothing is deleted, updated rows
+// replace the previous ones and removed rows are inserted again flagged as
+// deleted.
+func tableDiffStatements(table, stagingTable string, keys, columns []string, isDeletedColumn string) []diffStatement {
+	comparison := newTableComparison(table, stagingTable, keys, columns, isDeletedColumn)
+	quotedKeys := quoteIdentifiers(keys)
+	quotedColumns := quoteIdentifiers(columns)
+
+	if isDeletedColumn == "" {
+		return []diffStatement{
+			{
+				action: "delete removed and updated rows",
+				query: fmt.Sprintf(
+					"DELETE FROM %s WHERE (%s) IN (%s) OR (%s) IN (%s)",
+					table,
+					quotedKeys, comparison.removedKeys(),
+					quotedKeys, comparison.updatedKeys(),
+				),
+			},
+			{
+				action: "insert added and updated rows",
+				query: fmt.Sprintf(
+					"INSERT INTO %s (%s) SELECT %s FROM %s WHERE (%s) NOT IN (SELECT %s FROM %s)",
+					table, quotedColumns,
+					quotedColumns, stagingTable,
+					quotedKeys, quotedKeys, table,
+				),
+			},
+		}
+	}
+
+	otherColumns := make([]string, 0, len(columns))
+
+	for _, column := range columns {
+		if column != isDeletedColumn {
+			otherColumns = append(otherColumns, column)
+		}
+	}
+
+	// Tombstones have to be computed before the new rows are inserted
+	return []diffStatement{
+		{
+			action: "flag removed rows as deleted",
+			query: fmt.Sprintf(
+				"INSERT INTO %s (%s, %s) SELECT %s, 1 FROM %s WHERE (%s) IN (%s)",
+				table, quoteIdentifiers(otherColumns), QuoteIdentifier(isDeletedColumn),
+				quoteIdentifiers(otherColumns), comparison.table,
+				quotedKeys, comparison.removedKeys(),
+			),
+		},
+		{
+			action: "insert added and updated rows",
+			query: fmt.Sprintf(
+				"INSERT INTO %s (%s) SELECT %s FROM %s WHERE (%s) IN (%s) OR (%s) IN (%s)",
+				table, quotedColumns,
+				quotedColumns, stagingTable,
+				quotedKeys, comparison.addedKeys(),
+				quotedKeys, comparison.updatedKeys(),
+			),
+		},
+	}
+}
+
+// Apply the difference between a table and its staging copy to the table, see
+// tableDiffStatements. Without an is_deleted column, the updated rows are
+// missing from the table between their deletion and their insertion, so a
+// dictionary reloading in between misses them until its next reload. The
+// deletion waits for its mutation on every replica, and is allowed on
+// replicated tables although its subqueries are not deterministic.
+func ApplyTableDiff(conn driver.Conn, table, stagingTable string, keys, columns []string, isDeletedColumn string) error {
+	settings := clickhouse.Settings{}
+
+	if isDeletedColumn == "" {
+		settings["mutations_sync"] = 2
+		settings["lightweight_deletes_sync"] = 2
+
+		engine, err := GetTableEngine(conn, table)
+
+		if err != nil {
+			return err
+		}
+
+		if IsReplicatedEngine(engine) {
+			settings["allow_nondeterministic_mutations"] = 1
+		}
+	}
+
+	var (
+		ctx = clickhouse.Context(context.Background(), clickhouse.WithSettings(settings))
+	)
+
+	for _, statement := range tableDiffStatements(table, stagingTable, keys, columns, isDeletedColumn) {
+		if err := conn.Exec(ctx, statement.query); err != nil {
+			return fmt.Errorf("cannot %s: %s", statement.action, err)
+		}
+	}
+
+	return nil
+}
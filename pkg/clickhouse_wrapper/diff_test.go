@@ -0,0 +1,49 @@
+package clickhouse_wrapper
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTableDiffStatements(t *testing.T) {
+	statements := tableDiffStatements("db.source", "db.staging", []string{"id"}, []string{"id", "name"}, "")
+
+	expected := []diffStatement{
+		{
+			action: "delete removed and updated rows",
+			query: "DELETE FROM db.source WHERE (`id`) IN (SELECT `id` FROM db.source WHERE (`id`) NOT IN (SELECT `id` FROM db.staging)) " +
+				"OR (`id`) IN (SELECT `id` FROM (SELECT `id`, cityHash64(`id`, `name`) AS staging_hash FROM db.staging) " +
+				"INNER JOIN (SELECT `id`, cityHash64(`id`, `name`) AS source_hash FROM db.source) USING (`id`) WHERE staging_hash != source_hash)",
+		},
+		{
+			action: "insert added and updated rows",
+			query:  "INSERT INTO db.source (`id`, `name`) SELECT `id`, `name` FROM db.staging WHERE (`id`) NOT IN (SELECT `id` FROM db.source)",
+		},
+	}
+
+	if !reflect.DeepEqual(statements, expected) {
+		t.Errorf("statements = %#v, expected %#v", statements, expected)
+	}
+}
+
+func TestTableDiffStatementsIsDeleted(t *testing.T) {
+	statements := tableDiffStatements("db.source", "db.staging", []string{"id"}, []string{"id", "name", "deleted"}, "deleted")
+
+	if len(statements) != 2 {
+		t.Fatalf("got %d statements, expected 2", len(statements))
+	}
+
+	expectedTombstones := "INSERT INTO db.source (`id`, `name`, `deleted`) SELECT `id`, `name`, 1 FROM (SELECT * FROM db.source FINAL WHERE `deleted` = 0) " +
+		"WHERE (`id`) IN (SELECT `id` FROM (SELECT * FROM db.source FINAL WHERE `deleted` = 0) WHERE (`id`) NOT IN (SELECT `id` FROM db.staging))"
+
+	if statements[0].query != expectedTombstones {
+		t.Errorf("tombstones statement = %s, expected %s", statements[0].query, expectedTombstones)
+	}
+
+	for _, statement := range statements {
+		if strings.HasPrefix(statement.query, "DELETE") {
+			t.Errorf("rows must not be deleted with an is_deleted column: %s", statement.query)
+		}
+	}
+}
@@ -0,0 +1,716 @@
+package dictionary_data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Parsed ClickHouse column type, such as `Nullable(DateTime64(3, 'UTC'))`.
+type ColumnType struct {
+	Name string
+	// Raw arguments of parametric types, such as the precision of a decimal.
+	Args []string
+	// Nested types of Nullable, LowCardinality, Array, Map and Tuple.
+	Elems []*ColumnType
+
+	// Timezone of time types without a timezone argument.
+	timezone *time.Location
+}
+
+// Set the timezone of the time types without a timezone argument, nested
+// ones included. ClickHouse reads their values in the server timezone, which
+// is UTC unless set.
+func (t *ColumnType) SetTimezone(timezone *time.Location) {
+	t.timezone = timezone
+
+	for _, elem := range t.Elems {
+		elem.SetTimezone(timezone)
+	}
+}
+
+func ParseColumnType(definition string) (*ColumnType, error) {
+	definition = strings.TrimSpace(definition)
+	open := strings.IndexByte(definition, '(')
+
+	if open == -1 {
+		return &ColumnType{Name: definition}, nil
+	}
+
+	if !strings.HasSuffix(definition, ")") {
+		return nil, fmt.Errorf("invalid column type '%s'", definition)
+	}
+
+	columnType := &ColumnType{
+		Name: definition[:open],
+		Args: splitTypeArgs(definition[open+1 : len(definition)-1]),
+	}
+
+	switch columnType.Name {
+	case "Nullable", "LowCardinality", "Array", "Map", "Tuple":
+		for _, arg := range columnType.Args {
+			// Named tuple elements are of the form `name Type`
+			if columnType.Name == "Tuple" {
+				if name, elemType, found := strings.Cut(arg, " "); found && !strings.Contains(name, "(") {
+					arg = elemType
+				}
+			}
+
+			elem, err := ParseColumnType(arg)
+
+			if err != nil {
+				return nil, err
+			}
+
+			columnType.Elems = append(columnType.Elems, elem)
+		}
+	}
+
+	return columnType, nil
+}
+
+// Split the arguments of a parametric type on top-level commas, ignoring the
+// ones nested in parentheses or quotes.
+func splitTypeArgs(args string) []string {
+	result := make([]string, 0)
+	depth := 0
+	quoted := false
+	start := 0
+
+	for i := 0; i < len(args); i++ {
+		switch char := args[i]; {
+		case char == '\\' && quoted:
+			i++
+		case char == '\'':
+			quoted = !quoted
+		case quoted:
+		case char == '(':
+			depth++
+		case char == ')':
+			depth--
+		case char == ',' && depth == 0:
+			result = append(result, strings.TrimSpace(args[start:i]))
+			start = i + 1
+		}
+	}
+
+	return append(result, strings.TrimSpace(args[start:]))
+}
+
+func (t *ColumnType) String() string {
+	if len(t.Args) == 0 {
+		return t.Name
+	}
+
+	return fmt.Sprintf("%s(%s)", t.Name, strings.Join(t.Args, ", "))
+}
+
+var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// Go type expected by the ClickHouse driver for the column type.
+func (t *ColumnType) GoType() reflect.Type {
+	switch t.Name {
+	case "UInt8":
+		return reflect.TypeOf(uint8(0))
+	case "UInt16":
+		return reflect.TypeOf(uint16(0))
+	case "UInt32":
+		return reflect.TypeOf(uint32(0))
+	case "UInt64":
+		return reflect.TypeOf(uint64(0))
+	case "Int8":
+		return reflect.TypeOf(int8(0))
+	case "Int16":
+		return reflect.TypeOf(int16(0))
+	case "Int32":
+		return reflect.TypeOf(int32(0))
+	case "Int64":
+		return reflect.TypeOf(int64(0))
+	case "UInt128", "UInt256", "Int128", "Int256":
+		return reflect.TypeOf((*big.Int)(nil))
+	case "Float32":
+		return reflect.TypeOf(float32(0))
+	case "Float64":
+		return reflect.TypeOf(float64(0))
+	case "Bool":
+		return reflect.TypeOf(false)
+	case "String", "FixedString", "Enum8", "Enum16":
+		return reflect.TypeOf("")
+	case "Date", "Date32", "DateTime", "DateTime64":
+		return reflect.TypeOf(time.Time{})
+	case "Decimal", "Decimal32", "Decimal64", "Decimal128", "Decimal256":
+		return reflect.TypeOf(decimal.Decimal{})
+	case "UUID":
+		return reflect.TypeOf(uuid.UUID{})
+	case "IPv4", "IPv6":
+		return reflect.TypeOf(net.IP{})
+	case "Nullable":
+		return reflect.PointerTo(t.Elems[0].GoType())
+	case "LowCardinality":
+		return t.Elems[0].GoType()
+	case "Array":
+		return reflect.SliceOf(t.Elems[0].GoType())
+	case "Map":
+		return reflect.MapOf(t.Elems[0].GoType(), t.Elems[1].GoType())
+	default:
+		return interfaceType
+	}
+}
+
+// Convert a decoded value (from JSON, CSV, Parquet...) into the Go type the
+// ClickHouse driver expects for the column type.
+func (t *ColumnType) Coerce(value interface{}) (interface{}, error) {
+	if t.Name == "LowCardinality" {
+		return t.Elems[0].Coerce(value)
+	}
+
+	if t.Name == "Nullable" {
+		if isNull(value, t.Elems[0]) {
+			return nil, nil
+		}
+
+		return t.Elems[0].Coerce(value)
+	}
+
+	if value == nil {
+		return nil, fmt.Errorf("null value in non-Nullable %s column", t)
+	}
+
+	switch t.Name {
+	case "UInt8", "UInt16", "UInt32", "UInt64":
+		number, err := toUint(value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		goType := t.GoType()
+
+		if reflect.Zero(goType).OverflowUint(number) {
+			return nil, fmt.Errorf("%d overflows %s", number, t)
+		}
+
+		return reflect.ValueOf(number).Convert(goType).Interface(), nil
+	case "Int8", "Int16", "Int32", "Int64":
+		number, err := toInt(value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		goType := t.GoType()
+
+		if reflect.Zero(goType).OverflowInt(number) {
+			return nil, fmt.Errorf("%d overflows %s", number, t)
+		}
+
+		return reflect.ValueOf(number).Convert(goType).Interface(), nil
+	case "UInt128", "UInt256", "Int128", "Int256":
+		number, ok := new(big.Int).SetString(strings.TrimSpace(toString(value)), 10)
+
+		if !ok {
+			return nil, fmt.Errorf("invalid integer '%v'", value)
+		}
+
+		return number, nil
+	case "Float32":
+		number, err := toFloat(value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return float32(number), nil
+	case "Float64":
+		return toFloat(value)
+	case "Bool":
+		return toBool(value)
+	case "String", "FixedString":
+		return toString(value), nil
+	case "Enum8", "Enum16":
+		return toString(value), nil
+	case "Date", "Date32", "DateTime", "DateTime64":
+		return t.toTime(value)
+	case "Decimal", "Decimal32", "Decimal64", "Decimal128", "Decimal256":
+		if number, ok := value.(float64); ok {
+			return decimal.NewFromFloat(number), nil
+		}
+
+		return decimal.NewFromString(strings.TrimSpace(toString(value)))
+	case "UUID":
+		return uuid.Parse(strings.TrimSpace(toString(value)))
+	case "IPv4", "IPv6":
+		return t.toIP(value)
+	case "Array":
+		return t.toSlice(value)
+	case "Map":
+		return t.toMap(value)
+	case "Tuple":
+		return t.toTuple(value)
+	default:
+		return value, nil
+	}
+}
+
+// CSV and TSV have no null value, `\N` (as written by ClickHouse) and empty
+// fields of non-string types stand for null.
+func isNull(value interface{}, elem *ColumnType) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		if v == `\N` {
+			return true
+		}
+
+		return v == "" && elem.Name != "String" && elem.Name != "FixedString" && elem.Name != "LowCardinality"
+	default:
+		return false
+	}
+}
+
+// Whether a value of unknown type is null: nil, `\N` or an empty string.
+func isUntypedNull(value interface{}) bool {
+	return isNull(value, &ColumnType{})
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case []byte:
+		return string(v)
+	case []interface{}, map[string]interface{}:
+		data, err := json.Marshal(v)
+
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func toUint(value interface{}) (uint64, error) {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return 1, nil
+		}
+
+		return 0, nil
+	case float32, float64:
+		number := reflect.ValueOf(v).Float()
+
+		if number < 0 || number != math.Trunc(number) || number > math.MaxUint64 {
+			return 0, fmt.Errorf("%v is not an unsigned integer", v)
+		}
+
+		return uint64(number), nil
+	case json.Number, string:
+		text := strings.TrimSpace(toString(v))
+		number, err := strconv.ParseUint(text, 10, 64)
+
+		if err != nil {
+			// Integers written as floats, such as 1e3 or 12.0
+			if float, floatErr := strconv.ParseFloat(text, 64); floatErr == nil {
+				return toUint(float)
+			}
+
+			return 0, fmt.Errorf("'%s' is not an unsigned integer", text)
+		}
+
+		return number, nil
+	}
+
+	reflected := reflect.ValueOf(value)
+
+	switch {
+	case reflected.CanUint():
+		return reflected.Uint(), nil
+	case reflected.CanInt():
+		if reflected.Int() < 0 {
+			return 0, fmt.Errorf("%d is not an unsigned integer", reflected.Int())
+		}
+
+		return uint64(reflected.Int()), nil
+	default:
+		return 0, fmt.Errorf("cannot convert %T to an unsigned integer", value)
+	}
+}
+
+func toInt(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return 1, nil
+		}
+
+		return 0, nil
+	case float32, float64:
+		number := reflect.ValueOf(v).Float()
+
+		if number != math.Trunc(number) || number > math.MaxInt64 || number < math.MinInt64 {
+			return 0, fmt.Errorf("%v is not an integer", v)
+		}
+
+		return int64(number), nil
+	case json.Number, string:
+		text := strings.TrimSpace(toString(v))
+		number, err := strconv.ParseInt(text, 10, 64)
+
+		if err != nil {
+			if float, floatErr := strconv.ParseFloat(text, 64); floatErr == nil {
+				return toInt(float)
+			}
+
+			return 0, fmt.Errorf("'%s' is not an integer", text)
+		}
+
+		return number, nil
+	}
+
+	reflected := reflect.ValueOf(value)
+
+	switch {
+	case reflected.CanInt():
+		return reflected.Int(), nil
+	case reflected.CanUint():
+		if reflected.Uint() > math.MaxInt64 {
+			return 0, fmt.Errorf("%d overflows Int64", reflected.Uint())
+		}
+
+		return int64(reflected.Uint()), nil
+	default:
+		return 0, fmt.Errorf("cannot convert %T to an integer", value)
+	}
+}
+
+func toFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case json.Number, string:
+		text := strings.TrimSpace(toString(v))
+		number, err := strconv.ParseFloat(text, 64)
+
+		if err != nil {
+			return 0, fmt.Errorf("'%s' is not a number", text)
+		}
+
+		return number, nil
+	}
+
+	reflected := reflect.ValueOf(value)
+
+	switch {
+	case reflected.CanFloat():
+		return reflected.Float(), nil
+	case reflected.CanInt():
+		return float64(reflected.Int()), nil
+	case reflected.CanUint():
+		return float64(reflected.Uint()), nil
+	default:
+		return 0, fmt.Errorf("cannot convert %T to a number", value)
+	}
+}
+
+func toBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case json.Number, string:
+		text := strings.ToLower(strings.TrimSpace(toString(v)))
+
+		switch text {
+		case "true", "1", "yes", "y":
+			return true, nil
+		case "false", "0", "no", "n":
+			return false, nil
+		}
+
+		return false, fmt.Errorf("'%s' is not a boolean", text)
+	default:
+		number, err := toFloat(value)
+
+		if err != nil {
+			return false, err
+		}
+
+		return number != 0, nil
+	}
+}
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+func (t *ColumnType) location() *time.Location {
+	for _, arg := range t.Args {
+		if !strings.HasPrefix(arg, "'") {
+			continue
+		}
+
+		if location, err := time.LoadLocation(strings.Trim(arg, "'")); err == nil {
+			return location
+		}
+	}
+
+	if t.timezone != nil {
+		return t.timezone
+	}
+
+	return time.UTC
+}
+
+// Times are either strings in one of the usual layouts, or numbers of seconds
+// since the Unix epoch. Textual 8-digit values are compact YYYYMMDD dates when
+// they are valid ones.
+func (t *ColumnType) toTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case string, json.Number:
+		if text := strings.TrimSpace(toString(v)); len(text) == 8 {
+			if parsed, err := time.ParseInLocation("20060102", text, t.location()); err == nil {
+				return parsed, nil
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		text := strings.TrimSpace(v)
+
+		for _, layout := range timeLayouts {
+			if parsed, err := time.ParseInLocation(layout, text, t.location()); err == nil {
+				return parsed, nil
+			}
+		}
+
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return time.Time{}, fmt.Errorf("'%s' is not a valid %s", text, t)
+		}
+	}
+
+	seconds, err := toFloat(value)
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	whole, fraction := math.Modf(seconds)
+
+	return time.Unix(int64(whole), int64(fraction*1e9)).In(t.location()), nil
+}
+
+func (t *ColumnType) toIP(value interface{}) (net.IP, error) {
+	if ip, ok := value.(net.IP); ok {
+		return ip, nil
+	}
+
+	text := strings.TrimSpace(toString(value))
+
+	if ip := net.ParseIP(text); ip != nil {
+		if t.Name == "IPv4" {
+			if ip = ip.To4(); ip == nil {
+				return nil, fmt.Errorf("'%s' is not an IPv4 address", text)
+			}
+		}
+
+		return ip, nil
+	}
+
+	// IPv4 addresses stored as numbers
+	if t.Name == "IPv4" {
+		if number, err := toUint(value); err == nil && number <= math.MaxUint32 {
+			return net.IPv4(byte(number>>24), byte(number>>16), byte(number>>8), byte(number)).To4(), nil
+		}
+	}
+
+	return nil, fmt.Errorf("'%s' is not an %s address", text, t.Name)
+}
+
+// Arrays, maps and tuples may be given as JSON documents in text formats.
+func decodeNested(value interface{}) interface{} {
+	text, ok := value.(string)
+
+	if !ok {
+		return value
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+
+	var decoded interface{}
+
+	if err := decoder.Decode(&decoded); err != nil {
+		return value
+	}
+
+	return decoded
+}
+
+func assignable(value interface{}, goType reflect.Type) reflect.Value {
+	if value == nil {
+		return reflect.Zero(goType)
+	}
+
+	reflected := reflect.ValueOf(value)
+
+	if goType.Kind() == reflect.Pointer && reflected.Type() != goType {
+		pointer := reflect.New(goType.Elem())
+		pointer.Elem().Set(reflected)
+
+		return pointer
+	}
+
+	return reflected
+}
+
+func (t *ColumnType) toSlice(value interface{}) (interface{}, error) {
+	items, ok := decodeNested(value).([]interface{})
+
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %T to %s", value, t)
+	}
+
+	elem := t.Elems[0]
+	slice := reflect.MakeSlice(t.GoType(), 0, len(items))
+
+	for i, item := range items {
+		coerced, err := elem.Coerce(item)
+
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %s", i, err)
+		}
+
+		slice = reflect.Append(slice, assignable(coerced, elem.GoType()))
+	}
+
+	return slice.Interface(), nil
+}
+
+func (t *ColumnType) toMap(value interface{}) (interface{}, error) {
+	items, ok := decodeNested(value).(map[string]interface{})
+
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %T to %s", value, t)
+	}
+
+	keyType, valueType := t.Elems[0], t.Elems[1]
+	result := reflect.MakeMapWithSize(t.GoType(), len(items))
+
+	for key, item := range items {
+		coercedKey, err := keyType.Coerce(key)
+
+		if err != nil {
+			return nil, fmt.Errorf("key '%s': %s", key, err)
+		}
+
+		coercedValue, err := valueType.Coerce(item)
+
+		if err != nil {
+			return nil, fmt.Errorf("value of key '%s': %s", key, err)
+		}
+
+		result.SetMapIndex(assignable(coercedKey, keyType.GoType()), assignable(coercedValue, valueType.GoType()))
+	}
+
+	return result.Interface(), nil
+}
+
+func (t *ColumnType) toTuple(value interface{}) (interface{}, error) {
+	items, ok := decodeNested(value).([]interface{})
+
+	if !ok || len(items) != len(t.Elems) {
+		return nil, fmt.Errorf("cannot convert %v to %s", value, t)
+	}
+
+	result := make([]interface{}, len(items))
+
+	for i, item := range items {
+		coerced, err := t.Elems[i].Coerce(item)
+
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %s", i, err)
+		}
+
+		result[i] = coerced
+	}
+
+	return result, nil
+}
+
+// Default value of the column type, used in place of nulls in non-Nullable
+// columns, as ClickHouse does with input_format_null_as_default.
+func (t *ColumnType) DefaultValue() (interface{}, error) {
+	switch t.Name {
+	case "Nullable":
+		return nil, nil
+	case "LowCardinality":
+		return t.Elems[0].DefaultValue()
+	case "UInt128", "UInt256", "Int128", "Int256":
+		return big.NewInt(0), nil
+	case "Date", "Date32", "DateTime", "DateTime64":
+		return time.Unix(0, 0).In(t.location()), nil
+	case "IPv4":
+		return net.IPv4zero.To4(), nil
+	case "IPv6":
+		return net.IPv6zero, nil
+	case "Enum8", "Enum16":
+		// The default of an enum is its first value
+		if len(t.Args) == 0 {
+			return nil, fmt.Errorf("%s has no values", t)
+		}
+
+		name, _, _ := strings.Cut(t.Args[0], "=")
+
+		return strings.Trim(strings.TrimSpace(name), "'"), nil
+	case "Array":
+		return reflect.MakeSlice(t.GoType(), 0, 0).Interface(), nil
+	case "Map":
+		return reflect.MakeMap(t.GoType()).Interface(), nil
+	case "Tuple":
+		result := make([]interface{}, len(t.Elems))
+
+		for i, elem := range t.Elems {
+			value, err := elem.DefaultValue()
+
+			if err != nil {
+				return nil, err
+			}
+
+			result[i] = value
+		}
+
+		return result, nil
+	default:
+		return reflect.Zero(t.GoType()).Interface(), nil
+	}
+}
+
+// Decode JSON numbers as json.Number, so that big integers keep their
+// precision until they are coerced to the column type.
+func decodeJSON(data []byte, target interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	return decoder.Decode(target)
+}
@@ -0,0 +1,149 @@
+package dictionary_data
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseColumnType(t *testing.T) {
+	columnType, err := ParseColumnType("Map(String, Array(Nullable(DateTime64(3, 'Europe/Paris'))))")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if columnType.Name != "Map" || len(columnType.Elems) != 2 {
+		t.Fatalf("parsed %#v", columnType)
+	}
+
+	dateTime := columnType.Elems[1].Elems[0].Elems[0]
+
+	if dateTime.Name != "DateTime64" || !reflect.DeepEqual(dateTime.Args, []string{"3", "'Europe/Paris'"}) {
+		t.Errorf("parsed %#v", dateTime)
+	}
+}
+
+func TestCoerce(t *testing.T) {
+	tests := []struct {
+		columnType string
+		value      interface{}
+		expected   interface{}
+	}{
+		{"UInt8", "42", uint8(42)},
+		{"Int32", json.Number("-7"), int32(-7)},
+		{"Float64", "1.5", 1.5},
+		{"Bool", "true", true},
+		{"String", json.Number("12"), "12"},
+		{"Nullable(UInt64)", "", nil},
+		{"Nullable(String)", "", ""},
+		{"Nullable(String)", `\N`, nil},
+		{"LowCardinality(Nullable(String))", `\N`, nil},
+		{"LowCardinality(Nullable(String))", nil, nil},
+		{"LowCardinality(Nullable(String))", "", ""},
+		{"LowCardinality(String)", "EU", "EU"},
+		{"Array(UInt16)", "[1, 2]", []uint16{1, 2}},
+		{"Date", "2024-01-02", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"Date", "20240102", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"Date", json.Number("20240102"), time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"DateTime", "1704164645", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"DateTime", "99999999", time.Unix(99999999, 0).UTC()},
+		{"DateTime('Europe/Paris')", "2024-01-02 03:04:05", time.Date(2024, 1, 2, 2, 4, 5, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		columnType, err := ParseColumnType(test.columnType)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		value, err := columnType.Coerce(test.value)
+
+		if err != nil {
+			t.Errorf("%s.Coerce(%#v): %s", test.columnType, test.value, err)
+			continue
+		}
+
+		if expectedTime, ok := test.expected.(time.Time); ok {
+			if parsed, ok := value.(time.Time); !ok || !parsed.Equal(expectedTime) {
+				t.Errorf("%s.Coerce(%#v) = %#v, expected %s", test.columnType, test.value, value, expectedTime)
+			}
+
+			continue
+		}
+
+		if !reflect.DeepEqual(value, test.expected) {
+			t.Errorf("%s.Coerce(%#v) = %#v, expected %#v", test.columnType, test.value, value, test.expected)
+		}
+	}
+}
+
+func TestCoerceServerTimezone(t *testing.T) {
+	timezone, err := time.LoadLocation("America/New_York")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columnType, err := ParseColumnType("Array(Nullable(DateTime))")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columnType.SetTimezone(timezone)
+
+	value, err := columnType.Coerce(`["2024-01-01 00:00:00"]`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := time.Date(2024, 1, 1, 5, 0, 0, 0, time.UTC)
+
+	if times, ok := value.([]*time.Time); !ok || len(times) != 1 || !times[0].Equal(expected) {
+		t.Errorf("Coerce = %#v, expected [%s]", value, expected)
+	}
+
+	// Timezone arguments take precedence over the server timezone
+	columnType, err = ParseColumnType("DateTime('UTC')")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columnType.SetTimezone(timezone)
+
+	if value, err := columnType.Coerce("2024-01-01 00:00:00"); err != nil || !value.(time.Time).Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Coerce = %#v, %v, expected midnight UTC", value, err)
+	}
+}
+
+func TestCoerceErrors(t *testing.T) {
+	tests := []struct {
+		columnType string
+		value      interface{}
+	}{
+		{"UInt8", "256"},
+		{"Int8", "-129"},
+		{"UInt32", "-1"},
+		{"UInt64", nil},
+		{"LowCardinality(String)", nil},
+		{"Date", "yesterday"},
+		{"UUID", "not-a-uuid"},
+	}
+
+	for _, test := range tests {
+		columnType, err := ParseColumnType(test.columnType)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if value, err := columnType.Coerce(test.value); err == nil {
+			t.Errorf("%s.Coerce(%#v) = %#v, expected an error", test.columnType, test.value, value)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package dictionary_data
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Reader of delimiter-separated values. The first line is a header giving the
+// keys of the records, and every value is read as a string.
+type CSVReader struct {
+	input     *bufio.Reader
+	delimiter rune
+	quote     rune
+	escapes   bool
+	keys      []string
+	line      int
+	// Whether each field of the last record read is a `\N` null.
+	nulls []bool
+}
+
+// Create a CSV reader. A zero quote disables quoting. With escapes, values are
+// unescaped the way ClickHouse's TabSeparated format escapes them (`\t`, `\n`,
+// `\\`...), which is how TSV files are read.
+func NewCSVReader(input io.Reader, delimiter, quote rune, escapes bool) (*CSVReader, error) {
+	reader := &CSVReader{
+		input:     bufio.NewReader(input),
+		delimiter: delimiter,
+		quote:     quote,
+		escapes:   escapes,
+	}
+
+	header, err := reader.readRecord()
+
+	if err == io.EOF {
+		return reader, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot read header: %s", err)
+	}
+
+	for i, key := range header {
+		header[i] = strings.TrimSpace(key)
+	}
+
+	reader.keys = header
+
+	return reader, nil
+}
+
+func (r *CSVReader) Keys() []string {
+	return r.keys
+}
+
+func (r *CSVReader) Read() (map[string]interface{}, error) {
+	fields, err := r.readRecord()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) != len(r.keys) {
+		return nil, fmt.Errorf("line %d has %d fields, expected %d", r.line, len(fields), len(r.keys))
+	}
+
+	record := make(map[string]interface{}, len(fields))
+
+	for i, field := range fields {
+		if r.nulls[i] {
+			record[r.keys[i]] = nil
+		} else {
+			record[r.keys[i]] = field
+		}
+	}
+
+	return record, nil
+}
+
+func (r *CSVReader) readRecord() ([]string, error) {
+	for {
+		fields, err := r.readFields()
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Skip blank lines
+		if len(fields) == 1 && fields[0] == "" {
+			continue
+		}
+
+		return fields, nil
+	}
+}
+
+// Read the fields of the next line. With escapes, fields which are exactly
+// `\N` are the nulls written by ClickHouse, and are flagged in r.nulls.
+func (r *CSVReader) readFields() ([]string, error) {
+	fields := make([]string, 0)
+	r.nulls = r.nulls[:0]
+	var field strings.Builder
+	quoted := false
+	started := false
+	null := false
+
+	endField := func(value string) {
+		fields = append(fields, value)
+		r.nulls = append(r.nulls, null && value == "N")
+		field.Reset()
+		null = false
+	}
+
+	r.line++
+
+	for {
+		char, _, err := r.input.ReadRune()
+
+		if err == io.EOF {
+			if quoted {
+				return nil, fmt.Errorf("line %d: unterminated quoted field", r.line)
+			}
+
+			if !started && len(fields) == 0 {
+				return nil, io.EOF
+			}
+
+			endField(field.String())
+
+			return fields, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		started = true
+
+		switch {
+		case quoted && char == r.quote:
+			next, _, err := r.input.ReadRune()
+
+			if err == nil && next == r.quote {
+				field.WriteRune(r.quote)
+				continue
+			}
+
+			if err == nil {
+				r.input.UnreadRune()
+			}
+
+			quoted = false
+		case quoted:
+			if char == '\n' {
+				r.line++
+			}
+
+			field.WriteRune(char)
+		case r.quote != 0 && char == r.quote && field.Len() == 0:
+			quoted = true
+		case char == r.delimiter:
+			endField(field.String())
+		case char == '\n':
+			endField(strings.TrimSuffix(field.String(), "\r"))
+
+			return fields, nil
+		case r.escapes && char == '\\':
+			next, _, err := r.input.ReadRune()
+
+			if err != nil {
+				return nil, fmt.Errorf("line %d: dangling escape", r.line)
+			}
+
+			if next == 'N' && field.Len() == 0 {
+				null = true
+			}
+
+			field.WriteString(unescape(next))
+		default:
+			field.WriteRune(char)
+		}
+	}
+}
+
+func unescape(char rune) string {
+	switch char {
+	case 't':
+		return "\t"
+	case 'n':
+		return "\n"
+	case 'r':
+		return "\r"
+	case '0':
+		return "\x00"
+	case 'b':
+		return "\b"
+	case 'f':
+		return "\f"
+	default:
+		return string(char)
+	}
+}
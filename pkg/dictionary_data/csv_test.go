@@ -0,0 +1,126 @@
+package dictionary_data
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readAll(t *testing.T, reader Reader) []map[string]interface{} {
+	t.Helper()
+
+	records := make([]map[string]interface{}, 0)
+
+	for {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			return records
+		}
+
+		if err != nil {
+			t.Fatalf("cannot read record %d: %s", len(records)+1, err)
+		}
+
+		records = append(records, record)
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	input := "id, name\n1,\"Doe, John\"\n\n2,\"say \"\"hi\"\"\"\r\n3,\"multi\nline\"\n"
+
+	reader, err := NewCSVReader(strings.NewReader(input), ',', '"', false)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keys := reader.Keys(); !reflect.DeepEqual(keys, []string{"id", "name"}) {
+		t.Errorf("keys = %v", keys)
+	}
+
+	expected := []map[string]interface{}{
+		{"id": "1", "name": "Doe, John"},
+		{"id": "2", "name": `say "hi"`},
+		{"id": "3", "name": "multi\nline"},
+	}
+
+	if records := readAll(t, reader); !reflect.DeepEqual(records, expected) {
+		t.Errorf("records = %v, expected %v", records, expected)
+	}
+}
+
+func TestCSVReaderFieldCount(t *testing.T) {
+	reader, err := NewCSVReader(strings.NewReader("a,b\n1\n"), ',', '"', false)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := reader.Read(); err == nil {
+		t.Error("expected an error for a line with missing fields")
+	}
+}
+
+func TestCSVReaderUnterminatedQuote(t *testing.T) {
+	reader, err := NewCSVReader(strings.NewReader("a\n\"open\n"), ',', '"', false)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := reader.Read(); err == nil {
+		t.Error("expected an error for an unterminated quoted field")
+	}
+}
+
+func TestTSVReader(t *testing.T) {
+	input := "id\tname\n1\ttab\\there\n2\tline\\nbreak\\\\\n"
+
+	reader, err := NewReader("tsv", strings.NewReader(input), DefaultReaderOptions)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []map[string]interface{}{
+		{"id": "1", "name": "tab\there"},
+		{"id": "2", "name": "line\nbreak\\"},
+	}
+
+	if records := readAll(t, reader); !reflect.DeepEqual(records, expected) {
+		t.Errorf("records = %v, expected %v", records, expected)
+	}
+}
+
+func TestTSVReaderNulls(t *testing.T) {
+	input := "id\tname\tnote\n1\t\\N\t\\Nope\n\\N\tN\t\\\\N\n"
+
+	reader, err := NewReader("tsv", strings.NewReader(input), DefaultReaderOptions)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []map[string]interface{}{
+		{"id": "1", "name": nil, "note": "Nope"},
+		{"id": nil, "name": "N", "note": `\N`},
+	}
+
+	records := readAll(t, reader)
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatalf("records = %#v, expected %#v", records, expected)
+	}
+
+	columnType, err := ParseColumnType("Nullable(String)")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value, err := columnType.Coerce(records[0]["name"]); err != nil || value != nil {
+		t.Errorf("Coerce(\\N) = %#v, %v, expected nil", value, err)
+	}
+}
@@ -0,0 +1,115 @@
+package dictionary_data
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	lz4Magic   = []byte{0x04, 0x22, 0x4d, 0x18}
+	bzip2Magic = []byte("BZh")
+)
+
+// Extensions of the compressed sources, by compression.
+var compressionExtensions = map[string]string{
+	".gz":   "gzip",
+	".gzip": "gzip",
+	".zst":  "zstd",
+	".zstd": "zstd",
+	".lz4":  "lz4",
+	".bz2":  "bzip2",
+}
+
+// Compression of a source with the given header, detected from its magic
+// bytes, or from the extension of its name when they are unknown. Empty when
+// the source is not compressed.
+func detectCompression(header []byte, name string) string {
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return "gzip"
+	case bytes.HasPrefix(header, zstdMagic):
+		return "zstd"
+	case bytes.HasPrefix(header, lz4Magic):
+		return "lz4"
+	case bytes.HasPrefix(header, bzip2Magic):
+		return "bzip2"
+	default:
+		return compressionExtensions[strings.ToLower(filepath.Ext(name))]
+	}
+}
+
+// Reader closing both a decompressing reader and its underlying input.
+type decompressedReader struct {
+	io.Reader
+	close func()
+	input io.Closer
+}
+
+func (r *decompressedReader) Close() error {
+	r.close()
+	return r.input.Close()
+}
+
+// Decompress the input when it is gzip, zstd, lz4 or bzip2 compressed,
+// otherwise return it as is. Files other than stdin are returned unwrapped
+// when they are not compressed, so that they can still be read at random.
+func decompress(input io.ReadCloser, name string) (io.ReadCloser, error) {
+	header := make([]byte, len(zstdMagic))
+
+	if file, ok := input.(*os.File); ok && file != os.Stdin {
+		read, _ := file.ReadAt(header, 0)
+
+		if detectCompression(header[:read], name) == "" {
+			return input, nil
+		}
+	}
+
+	buffered := bufio.NewReader(input)
+	header, _ = buffered.Peek(len(zstdMagic))
+
+	var reader io.Reader
+	var closeReader = func() {}
+
+	switch compression := detectCompression(header, name); compression {
+	case "gzip":
+		gzipReader, err := gzip.NewReader(buffered)
+
+		if err != nil {
+			input.Close()
+			return nil, fmt.Errorf("cannot decompress %s data: %s", compression, err)
+		}
+
+		reader = gzipReader
+		closeReader = func() { gzipReader.Close() }
+	case "zstd":
+		zstdReader, err := zstd.NewReader(buffered)
+
+		if err != nil {
+			input.Close()
+			return nil, fmt.Errorf("cannot decompress %s data: %s", compression, err)
+		}
+
+		reader = zstdReader
+		closeReader = zstdReader.Close
+	case "lz4":
+		reader = lz4.NewReader(buffered)
+	case "bzip2":
+		reader = bzip2.NewReader(buffered)
+	default:
+		reader = buffered
+	}
+
+	return &decompressedReader{Reader: reader, close: closeReader, input: input}, nil
+}
@@ -0,0 +1,221 @@
+package dictionary_data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Dictionary data as consumed by `syncDictionary`: the keys give the columns
+// of the source table, in order, and each value maps those keys to the data.
+type DictionarySynchronizationData struct {
+	Keys   []string                 `json:"keys"`
+	Values []map[string]interface{} `json:"values"`
+}
+
+// Reader of the `{"keys": [...], "values": [{...}, ...]}` format. The values
+// are decoded one by one as long as `keys` comes before `values` in the
+// document, otherwise the values have to be buffered until the keys are found.
+type JSONReader struct {
+	decoder  *json.Decoder
+	keys     []string
+	buffered []map[string]interface{}
+	inValues bool
+}
+
+func NewJSONReader(input io.Reader) (*JSONReader, error) {
+	reader := &JSONReader{decoder: json.NewDecoder(input)}
+	reader.decoder.UseNumber()
+
+	if err := expectDelim(reader.decoder, '{'); err != nil {
+		return nil, err
+	}
+
+	for reader.decoder.More() {
+		token, err := reader.decoder.Token()
+
+		if err != nil {
+			return nil, err
+		}
+
+		field, ok := token.(string)
+
+		if !ok {
+			return nil, fmt.Errorf("unexpected token %v in dictionary data", token)
+		}
+
+		switch field {
+		case "keys":
+			if err := reader.decoder.Decode(&reader.keys); err != nil {
+				return nil, fmt.Errorf("cannot decode keys: %s", err)
+			}
+
+			if reader.buffered != nil {
+				return reader, nil
+			}
+		case "values":
+			if reader.keys != nil {
+				if err := expectDelim(reader.decoder, '['); err != nil {
+					return nil, err
+				}
+
+				reader.inValues = true
+
+				return reader, nil
+			}
+
+			if err := reader.decoder.Decode(&reader.buffered); err != nil {
+				return nil, fmt.Errorf("cannot decode values: %s", err)
+			}
+		default:
+			var ignored json.RawMessage
+
+			if err := reader.decoder.Decode(&ignored); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if reader.keys == nil {
+		return nil, fmt.Errorf("missing 'keys' in dictionary data")
+	}
+
+	return reader, nil
+}
+
+func (r *JSONReader) Keys() []string {
+	return r.keys
+}
+
+func (r *JSONReader) Read() (map[string]interface{}, error) {
+	if !r.inValues {
+		if len(r.buffered) == 0 {
+			return nil, io.EOF
+		}
+
+		record := r.buffered[0]
+		r.buffered = r.buffered[1:]
+
+		return record, nil
+	}
+
+	if !r.decoder.More() {
+		r.inValues = false
+
+		if err := expectDelim(r.decoder, ']'); err != nil {
+			return nil, err
+		}
+
+		return nil, io.EOF
+	}
+
+	var record map[string]interface{}
+
+	if err := r.decoder.Decode(&record); err != nil {
+		return nil, fmt.Errorf("cannot decode value: %s", err)
+	}
+
+	return record, nil
+}
+
+// Reader of newline-delimited JSON, one object per line. The keys are taken
+// from the first object, in the order they appear in it.
+type NDJSONReader struct {
+	decoder *json.Decoder
+	keys    []string
+	first   map[string]interface{}
+}
+
+func NewNDJSONReader(input io.Reader) (*NDJSONReader, error) {
+	reader := &NDJSONReader{decoder: json.NewDecoder(input)}
+	reader.decoder.UseNumber()
+
+	var raw json.RawMessage
+
+	if err := reader.decoder.Decode(&raw); err != nil {
+		if err == io.EOF {
+			return reader, nil
+		}
+
+		return nil, fmt.Errorf("cannot decode first line: %s", err)
+	}
+
+	keys, err := objectKeys(raw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	reader.keys = keys
+
+	if err := decodeJSON(raw, &reader.first); err != nil {
+		return nil, err
+	}
+
+	return reader, nil
+}
+
+func (r *NDJSONReader) Keys() []string {
+	return r.keys
+}
+
+func (r *NDJSONReader) Read() (map[string]interface{}, error) {
+	if r.first != nil {
+		record := r.first
+		r.first = nil
+
+		return record, nil
+	}
+
+	var record map[string]interface{}
+
+	if err := r.decoder.Decode(&record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// Keys of a JSON object, in the order they appear in the document.
+func objectKeys(raw json.RawMessage) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+
+	if err := expectDelim(decoder, '{'); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0)
+
+	for decoder.More() {
+		token, err := decoder.Token()
+
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, token.(string))
+
+		var ignored json.RawMessage
+
+		if err := decoder.Decode(&ignored); err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+
+	if err != nil {
+		return err
+	}
+
+	if token != delim {
+		return fmt.Errorf("expected '%s' in dictionary data, got %v", delim, token)
+	}
+
+	return nil
+}
@@ -0,0 +1,264 @@
+package dictionary_data
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Mapping of one column of the source table from the dictionary data.
+type ColumnMapping struct {
+	// Field of the data holding the column value, defaulting to the column
+	// name.
+	Field string `mapstructure:"field"`
+	// Value used when the field is missing or null, empty and `\N` text
+	// values being null.
+	Default interface{} `mapstructure:"default"`
+	// Transforms applied in order to string values: lowercase, uppercase,
+	// trim, or date:<layout> to parse a date with a Go time layout.
+	Transforms []string `mapstructure:"transforms"`
+}
+
+type MappingOptions struct {
+	// Mappings of the columns, by column name.
+	Columns map[string]ColumnMapping
+	// Drop the fields which are not columns of the table.
+	DropExtraFields bool
+	// Columns of the table, used to drop extra fields.
+	TableColumns []string
+}
+
+func (o MappingOptions) IsEmpty() bool {
+	return len(o.Columns) == 0 && !o.DropExtraFields
+}
+
+type columnSource struct {
+	column  string
+	field   string
+	mapping ColumnMapping
+}
+
+// Reader renaming, defaulting and transforming the fields of the records of
+// another reader into the columns of a table.
+type MappedReader struct {
+	reader  Reader
+	keys    []string
+	sources []columnSource
+}
+
+func NewMappedReader(reader Reader, options MappingOptions) (*MappedReader, error) {
+	for column, mapping := range options.Columns {
+		for _, transform := range mapping.Transforms {
+			if err := validateTransform(transform); err != nil {
+				return nil, fmt.Errorf("column '%s': %s", column, err)
+			}
+		}
+	}
+
+	fieldColumns := make(map[string]string)
+
+	for column, mapping := range options.Columns {
+		field := mapping.Field
+
+		if field == "" {
+			field = column
+		}
+
+		fieldColumns[field] = column
+	}
+
+	tableColumns := make(map[string]bool)
+
+	for _, column := range options.TableColumns {
+		tableColumns[column] = true
+	}
+
+	mapped := &MappedReader{reader: reader}
+	seenColumns := make(map[string]bool)
+
+	addColumn := func(column, field string) {
+		if seenColumns[column] {
+			return
+		}
+
+		if options.DropExtraFields && !tableColumns[column] {
+			return
+		}
+
+		seenColumns[column] = true
+
+		mapped.keys = append(mapped.keys, column)
+		mapped.sources = append(mapped.sources, columnSource{column: column, field: field, mapping: options.Columns[column]})
+	}
+
+	// Fields keep the order of the data, mapped columns whose field is absent
+	// from the data come last
+	for _, field := range reader.Keys() {
+		if column, ok := fieldColumns[field]; ok {
+			addColumn(column, field)
+		} else if _, ok := options.Columns[field]; !ok {
+			addColumn(field, field)
+		}
+	}
+
+	remaining := make([]string, 0)
+
+	for column := range options.Columns {
+		if !seenColumns[column] {
+			remaining = append(remaining, column)
+		}
+	}
+
+	sort.Strings(remaining)
+
+	for _, column := range remaining {
+		field := options.Columns[column].Field
+
+		if field == "" {
+			field = column
+		}
+
+		addColumn(column, field)
+	}
+
+	return mapped, nil
+}
+
+func (r *MappedReader) Keys() []string {
+	return r.keys
+}
+
+func (r *MappedReader) Read() (map[string]interface{}, error) {
+	values, err := r.reader.Read()
+
+	if err != nil {
+		return nil, err
+	}
+
+	record := make(map[string]interface{}, len(r.sources))
+
+	for _, source := range r.sources {
+		value, ok := values[source.field]
+
+		// Empty and `\N` text values are null, and replaced by the default
+		// when there is one. Empty strings are kept otherwise, as they are
+		// valid String values.
+		if !ok || isUntypedNull(value) {
+			switch {
+			case source.mapping.Default != nil:
+				value = source.mapping.Default
+			case !ok:
+				continue
+			case value == `\N`:
+				value = nil
+			}
+		}
+
+		if value == nil {
+			record[source.column] = nil
+			continue
+		}
+
+		for _, transform := range source.mapping.Transforms {
+			if value, err = applyTransform(transform, value); err != nil {
+				return nil, fmt.Errorf("column '%s': %s", source.column, err)
+			}
+		}
+
+		record[source.column] = value
+	}
+
+	return record, nil
+}
+
+func validateTransform(transform string) error {
+	name, layout, _ := strings.Cut(transform, ":")
+
+	switch name {
+	case "lowercase", "uppercase", "trim":
+		return nil
+	case "date":
+		if layout == "" {
+			return fmt.Errorf("date transform needs a layout, such as date:2006-01-02")
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unknown transform '%s': expected lowercase, uppercase, trim or date:<layout>", transform)
+	}
+}
+
+// Apply a transform to a string value, other values being kept as is.
+func applyTransform(transform string, value interface{}) (interface{}, error) {
+	text, ok := value.(string)
+
+	if !ok {
+		return value, nil
+	}
+
+	name, layout, _ := strings.Cut(transform, ":")
+
+	switch name {
+	case "lowercase":
+		return strings.ToLower(text), nil
+	case "uppercase":
+		return strings.ToUpper(text), nil
+	case "trim":
+		return strings.TrimSpace(text), nil
+	case "date":
+		parsed, err := time.Parse(layout, strings.TrimSpace(text))
+
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse '%s' with layout '%s'", text, layout)
+		}
+
+		return parsed, nil
+	default:
+		return value, nil
+	}
+}
+
+// Parse mappings given as flags: `field=column` renames, `column=value`
+// defaults and `column=transform` transforms.
+func ParseColumnMappings(renames, defaults, transforms []string) (map[string]ColumnMapping, error) {
+	columns := make(map[string]ColumnMapping)
+
+	for _, rename := range renames {
+		field, column, ok := strings.Cut(rename, "=")
+
+		if !ok || field == "" || column == "" {
+			return nil, fmt.Errorf("invalid mapping '%s': expected field=column", rename)
+		}
+
+		mapping := columns[column]
+		mapping.Field = field
+		columns[column] = mapping
+	}
+
+	for _, value := range defaults {
+		column, defaultValue, ok := strings.Cut(value, "=")
+
+		if !ok || column == "" {
+			return nil, fmt.Errorf("invalid default '%s': expected column=value", value)
+		}
+
+		mapping := columns[column]
+		mapping.Default = defaultValue
+		columns[column] = mapping
+	}
+
+	for _, value := range transforms {
+		column, transform, ok := strings.Cut(value, "=")
+
+		if !ok || column == "" {
+			return nil, fmt.Errorf("invalid transform '%s': expected column=transform", value)
+		}
+
+		mapping := columns[column]
+		mapping.Transforms = append(mapping.Transforms, transform)
+		columns[column] = mapping
+	}
+
+	return columns, nil
+}
@@ -0,0 +1,58 @@
+package dictionary_data
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMappedReader(t *testing.T) {
+	input := "code,name,region,extra\nFR, France ,,x\nDE,Germany,\\N,y\n,\\N,EU,z\n"
+
+	reader, err := NewCSVReader(strings.NewReader(input), ',', '"', false)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mapped, err := NewMappedReader(reader, MappingOptions{
+		Columns: map[string]ColumnMapping{
+			"id":      {Field: "code", Transforms: []string{"lowercase"}},
+			"name":    {Transforms: []string{"trim"}},
+			"region":  {Default: "unknown"},
+			"country": {Default: "none"},
+		},
+		DropExtraFields: true,
+		TableColumns:    []string{"id", "name", "region", "country"},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keys := mapped.Keys(); !reflect.DeepEqual(keys, []string{"id", "name", "region", "country"}) {
+		t.Errorf("keys = %v", keys)
+	}
+
+	expected := []map[string]interface{}{
+		{"id": "fr", "name": "France", "region": "unknown", "country": "none"},
+		{"id": "de", "name": "Germany", "region": "unknown", "country": "none"},
+		{"id": "", "name": nil, "region": "EU", "country": "none"},
+	}
+
+	if records := readAll(t, mapped); !reflect.DeepEqual(records, expected) {
+		t.Errorf("records = %#v, expected %#v", records, expected)
+	}
+}
+
+func TestMappedReaderUnknownTransform(t *testing.T) {
+	reader, err := NewCSVReader(strings.NewReader("a\n1\n"), ',', '"', false)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewMappedReader(reader, MappingOptions{Columns: map[string]ColumnMapping{"a": {Transforms: []string{"reverse"}}}}); err == nil {
+		t.Error("expected an error for an unknown transform")
+	}
+}
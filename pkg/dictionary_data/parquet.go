@@ -0,0 +1,82 @@
+package dictionary_data
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Reader of Parquet files. The keys are the top-level columns of the file
+// schema. Parquet needs random access to the file, so inputs which are not
+// files (such as stdin) are first spooled to a temporary file.
+type ParquetReader struct {
+	reader *parquet.Reader
+	keys   []string
+	spool  *os.File
+}
+
+func NewParquetReader(input io.Reader) (*ParquetReader, error) {
+	file, ok := input.(*os.File)
+	var spool *os.File
+	var err error
+
+	if !ok || file == os.Stdin {
+		if spool, err = os.CreateTemp("", "clickhouse-toolbox-*.parquet"); err != nil {
+			return nil, err
+		}
+
+		os.Remove(spool.Name())
+
+		if _, err := io.Copy(spool, input); err != nil {
+			spool.Close()
+			return nil, err
+		}
+
+		file = spool
+	}
+
+	stat, err := file.Stat()
+
+	if err != nil {
+		return nil, err
+	}
+
+	parquetFile, err := parquet.OpenFile(file, stat.Size())
+
+	if err != nil {
+		if spool != nil {
+			spool.Close()
+		}
+
+		return nil, fmt.Errorf("cannot open parquet file: %s", err)
+	}
+
+	reader := parquet.NewReader(parquetFile)
+	keys := make([]string, 0)
+
+	for _, field := range reader.Schema().Fields() {
+		keys = append(keys, field.Name())
+	}
+
+	return &ParquetReader{reader: reader, keys: keys, spool: spool}, nil
+}
+
+func (r *ParquetReader) Keys() []string {
+	return r.keys
+}
+
+func (r *ParquetReader) Read() (map[string]interface{}, error) {
+	record := make(map[string]interface{})
+
+	if err := r.reader.Read(&record); err != nil {
+		if err == io.EOF && r.spool != nil {
+			r.spool.Close()
+		}
+
+		return nil, err
+	}
+
+	return record, nil
+}
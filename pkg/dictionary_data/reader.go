@@ -0,0 +1,121 @@
+package dictionary_data
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A Reader streams the records of dictionary data, one at a time, so that
+// large payloads never need to be loaded in memory at once.
+type Reader interface {
+	// Names of the fields of each record, in insertion order.
+	Keys() []string
+	// Read the next record. Returns io.EOF once every record has been read.
+	Read() (map[string]interface{}, error)
+}
+
+var Formats = []string{"json", "ndjson", "csv", "tsv", "parquet"}
+
+type ReaderOptions struct {
+	// Field delimiter of the CSV format.
+	CSVDelimiter rune
+	// Quote character of the CSV format, zero to disable quoting.
+	CSVQuote rune
+}
+
+var DefaultReaderOptions = ReaderOptions{
+	CSVDelimiter: ',',
+	CSVQuote:     '"',
+}
+
+func NewReader(format string, input io.Reader, options ReaderOptions) (Reader, error) {
+	switch format {
+	case "json":
+		return NewJSONReader(input)
+	case "ndjson":
+		return NewNDJSONReader(input)
+	case "csv":
+		return NewCSVReader(input, options.CSVDelimiter, options.CSVQuote, false)
+	case "tsv":
+		return NewCSVReader(input, '\t', 0, true)
+	case "parquet":
+		return NewParquetReader(input)
+	default:
+		return nil, fmt.Errorf("unknown dictionary data format '%s': expected one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// Guess the format of a dictionary data source from its extension, ignoring
+// the compression extension, defaulting to json.
+func DetectFormat(source string) string {
+	path := strings.ToLower(sourcePath(source))
+
+	if _, ok := compressionExtensions[filepath.Ext(path)]; ok {
+		path = strings.TrimSuffix(path, filepath.Ext(path))
+	}
+
+	switch filepath.Ext(path) {
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	case ".csv":
+		return "csv"
+	case ".tsv", ".tab":
+		return "tsv"
+	case ".parquet":
+		return "parquet"
+	default:
+		return "json"
+	}
+}
+
+// Open the given dictionary data source, `-` being the standard input, which
+// can also be an HTTP(S) or S3 URL. Compressed data is decompressed.
+func Open(source string, options SourceOptions) (io.ReadCloser, error) {
+	if source == "-" {
+		return decompress(io.NopCloser(os.Stdin), "")
+	}
+
+	if IsRemoteSource(source) {
+		return openRemote(source, options)
+	}
+
+	file, err := os.Open(source)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decompress(file, source)
+}
+
+// Rough estimate of the memory used by a decoded value, used to bound the
+// size of insert batches.
+func ApproximateSize(value interface{}) int {
+	switch v := value.(type) {
+	case nil:
+		return 1
+	case string:
+		return len(v)
+	case []interface{}:
+		size := 0
+
+		for _, item := range v {
+			size += ApproximateSize(item)
+		}
+
+		return size
+	case map[string]interface{}:
+		size := 0
+
+		for key, item := range v {
+			size += len(key) + ApproximateSize(item)
+		}
+
+		return size
+	default:
+		return 8
+	}
+}
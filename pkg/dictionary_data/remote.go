@@ -0,0 +1,303 @@
+package dictionary_data
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Options of the dictionary data sources fetched over HTTP(S) or from an
+// S3-compatible object storage.
+type SourceOptions struct {
+	// Extra headers of HTTP(S) requests, as `Name: value`.
+	Headers []string
+	// Bearer token sent in the Authorization header of HTTP(S) requests.
+	BearerToken string
+	// Directory keeping the last fetched content of each URL, along with its
+	// ETag and Last-Modified headers, so that unchanged content is not
+	// downloaded again. Empty to disable the cache.
+	CacheDirectory string
+
+	// Endpoint of the S3-compatible object storage, such as
+	// `http://localhost:9000` for a local MinIO. Buckets are addressed by
+	// path on custom endpoints, and by virtual host on AWS.
+	S3Endpoint        string
+	S3Region          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3SessionToken    string
+}
+
+// Default cache directory of the fetched dictionary data, in the user cache
+// directory. Empty if the user has no cache directory.
+func DefaultCacheDirectory() string {
+	cacheDirectory, err := os.UserCacheDir()
+
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(cacheDirectory, "clickhouse-toolbox", "dictionaries")
+}
+
+// Whether the source is fetched from an URL rather than read from a local
+// file.
+func IsRemoteSource(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "s3://")
+}
+
+// Path of the source, without the scheme, host and query of URLs.
+func sourcePath(source string) string {
+	if !IsRemoteSource(source) {
+		return source
+	}
+
+	parsed, err := url.Parse(source)
+
+	if err != nil {
+		return source
+	}
+
+	return parsed.Path
+}
+
+// Fetch the content of an HTTP(S) or S3 URL. The content is decompressed when
+// it is compressed.
+func openRemote(source string, options SourceOptions) (io.ReadCloser, error) {
+	var request *http.Request
+	var err error
+
+	if strings.HasPrefix(source, "s3://") {
+		request, err = newS3Request(source, options)
+	} else {
+		request, err = newHTTPRequest(source, options)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{}
+
+	var body io.ReadCloser
+
+	if options.CacheDirectory == "" {
+		body, err = fetch(client, request, source)
+	} else {
+		body, err = fetchCached(client, request, source, options)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decompress(body, sourcePath(source))
+}
+
+func newHTTPRequest(source string, options SourceOptions) (*http.Request, error) {
+	request, err := http.NewRequest(http.MethodGet, source, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, header := range options.Headers {
+		name, value, ok := strings.Cut(header, ":")
+
+		if !ok {
+			return nil, fmt.Errorf("invalid header '%s': expected 'Name: value'", header)
+		}
+
+		request.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	if options.BearerToken != "" {
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", options.BearerToken))
+	}
+
+	return request, nil
+}
+
+func fetch(client *http.Client, request *http.Request, source string) (io.ReadCloser, error) {
+	response, err := client.Do(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		return nil, responseError(response, source)
+	}
+
+	return response.Body, nil
+}
+
+func responseError(response *http.Response, source string) error {
+	message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+
+	return fmt.Errorf("cannot fetch %s: %s: %s", redactURL(source), response.Status, strings.TrimSpace(string(message)))
+}
+
+// Remove the credentials and query of an URL, so that it can be logged.
+func redactURL(source string) string {
+	parsed, err := url.Parse(source)
+
+	if err != nil {
+		return source
+	}
+
+	parsed.User = nil
+	parsed.RawQuery = ""
+
+	return parsed.String()
+}
+
+// Validators of the cached content of an URL.
+type cacheEntry struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag"`
+	LastModified string `json:"last_modified"`
+}
+
+// Key of the cached content of an URL. Fetching the same URL from another S3
+// endpoint or with other credentials or headers may give other content, so
+// they are part of the key.
+func cacheKey(request *http.Request, source string, options SourceOptions) string {
+	key := sha256.New()
+
+	fmt.Fprintf(key, "%s\x00%s\x00", source, request.URL.String())
+
+	for _, header := range options.Headers {
+		fmt.Fprintf(key, "%s\x00", header)
+	}
+
+	fmt.Fprintf(key, "%s\x00%s\x00%s\x00%s\x00%s", options.BearerToken, options.S3Region, options.S3AccessKeyID, options.S3SecretAccessKey, options.S3SessionToken)
+
+	return hex.EncodeToString(key.Sum(nil))
+}
+
+// Fetch an URL, sending the validators of its cached content. The cached
+// content is used when the server answers that it was not modified, and
+// replaced otherwise.
+func fetchCached(client *http.Client, request *http.Request, source string, options SourceOptions) (io.ReadCloser, error) {
+	cacheDirectory := options.CacheDirectory
+
+	if err := os.MkdirAll(cacheDirectory, 0o700); err != nil {
+		return nil, fmt.Errorf("cannot create cache directory: %s", err)
+	}
+
+	cachePath := filepath.Join(cacheDirectory, cacheKey(request, source, options))
+
+	var entry cacheEntry
+
+	if content, err := os.ReadFile(cachePath + ".json"); err == nil && json.Unmarshal(content, &entry) == nil {
+		if _, err := os.Stat(cachePath + ".data"); err == nil {
+			if entry.ETag != "" {
+				request.Header.Set("If-None-Match", entry.ETag)
+			}
+
+			if entry.LastModified != "" {
+				request.Header.Set("If-Modified-Since", entry.LastModified)
+			}
+		}
+	}
+
+	response, err := client.Do(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotModified {
+		return os.Open(cachePath + ".data")
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, responseError(response, source)
+	}
+
+	entry = cacheEntry{
+		URL:          redactURL(source),
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+	}
+
+	spool, err := os.CreateTemp(cacheDirectory, "fetch-*")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.Copy(spool, response.Body); err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		return nil, fmt.Errorf("cannot fetch %s: %s", redactURL(source), err)
+	}
+
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		return nil, err
+	}
+
+	// Content without validators cannot be revalidated, so it is not kept
+	if entry.ETag == "" && entry.LastModified == "" {
+		os.Remove(spool.Name())
+		return spool, nil
+	}
+
+	content, err := json.Marshal(entry)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(spool.Name(), cachePath+".data"); err != nil {
+		os.Remove(spool.Name())
+		return spool, nil
+	}
+
+	// The validators are written once the content is, so that they never
+	// describe a partially written content
+	if err := writeFileAtomically(cachePath+".json", content); err != nil {
+		os.Remove(cachePath + ".data")
+	}
+
+	return spool, nil
+}
+
+// Write a file through a temporary file renamed once written, so that readers
+// never see it partially written.
+func writeFileAtomically(path string, content []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "write-*")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(content)
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(file.Name())
+	}
+
+	return err
+}
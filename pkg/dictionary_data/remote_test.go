@@ -0,0 +1,89 @@
+package dictionary_data
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenRemoteCached(t *testing.T) {
+	requests := 0
+	notModified := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		etag := `"` + r.Header.Get("Authorization") + `"`
+
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		io.WriteString(w, "content for "+r.Header.Get("Authorization"))
+	}))
+
+	defer server.Close()
+
+	options := SourceOptions{BearerToken: "first", CacheDirectory: t.TempDir()}
+
+	read := func(options SourceOptions) string {
+		t.Helper()
+
+		input, err := Open(server.URL+"/data.csv", options)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer input.Close()
+
+		content, err := io.ReadAll(input)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return string(content)
+	}
+
+	if content := read(options); content != "content for Bearer first" {
+		t.Errorf("content = %q", content)
+	}
+
+	if content := read(options); content != "content for Bearer first" || notModified != 1 {
+		t.Errorf("content = %q after %d not modified answers, expected the cached content", content, notModified)
+	}
+
+	// Other credentials do not share the cached content
+	options.BearerToken = "second"
+
+	if content := read(options); content != "content for Bearer second" || notModified != 1 {
+		t.Errorf("content = %q after %d not modified answers, expected fresh content", content, notModified)
+	}
+
+	if requests != 3 {
+		t.Errorf("%d requests sent, expected 3", requests)
+	}
+
+	entries, err := filepath.Glob(filepath.Join(options.CacheDirectory, "*"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 4 {
+		t.Errorf("cache holds %v, expected the content and validators of 2 entries", entries)
+	}
+
+	for _, entry := range entries {
+		if info, err := os.Stat(entry); err != nil || info.Size() == 0 {
+			t.Errorf("cache entry %s is empty", entry)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package dictionary_data
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const s3DefaultRegion = "us-east-1"
+
+// Hash of an empty payload, as sent by GET requests.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// Build the request fetching an `s3://bucket/key` URL, signed with AWS
+// Signature Version 4 when credentials are given.
+func newS3Request(source string, options SourceOptions) (*http.Request, error) {
+	parsed, err := url.Parse(source)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bucket := parsed.Host
+	key := strings.TrimPrefix(parsed.Path, "/")
+
+	if bucket == "" || key == "" {
+		return nil, fmt.Errorf("invalid S3 URL '%s': expected s3://bucket/key", source)
+	}
+
+	region := options.S3Region
+
+	if region == "" {
+		region = s3DefaultRegion
+	}
+
+	var objectURL string
+
+	if options.S3Endpoint != "" {
+		objectURL = fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(options.S3Endpoint, "/"), bucket, s3EncodePath(key))
+	} else {
+		objectURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, s3EncodePath(key))
+	}
+
+	request, err := http.NewRequest(http.MethodGet, objectURL, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if options.S3AccessKeyID != "" {
+		signS3Request(request, region, options, time.Now().UTC())
+	}
+
+	return request, nil
+}
+
+func signS3Request(request *http.Request, region string, options SourceOptions, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", request.URL.Host, emptyPayloadHash, amzDate)
+
+	if options.S3SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", options.S3SessionToken)
+
+		signedHeaders += ";x-amz-security-token"
+		canonicalHeaders += fmt.Sprintf("x-amz-security-token:%s\n", options.S3SessionToken)
+	}
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		request.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, region)
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(canonicalRequestHash[:]),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+options.S3SecretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		options.S3AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
+
+// Encode an object key as S3 expects it in canonical requests: every byte but
+// the unreserved characters and the slashes is percent-encoded.
+func s3EncodePath(key string) string {
+	var builder strings.Builder
+
+	for _, b := range []byte(key) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~', b == '/':
+			builder.WriteByte(b)
+		default:
+			fmt.Fprintf(&builder, "%%%02X", b)
+		}
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,5 @@
+//go:build mysql
+
+package dictionary_data
+
+import _ "github.com/go-sql-driver/mysql"
@@ -0,0 +1,5 @@
+//go:build postgres
+
+package dictionary_data
+
+import _ "github.com/jackc/pgx/v5/stdlib"
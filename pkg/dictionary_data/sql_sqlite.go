@@ -0,0 +1,4 @@
+package dictionary_data
+
+// SQLite is built in, so that SQL sources can be used without a server.
+import _ "modernc.org/sqlite"
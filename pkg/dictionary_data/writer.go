@@ -0,0 +1,345 @@
+package dictionary_data
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// A Writer writes records of dictionary data in one of the formats read by
+// the readers, so that exported data can be synchronized again.
+type Writer interface {
+	// Write a record, the values being in the order of the writer keys.
+	Write(values []interface{}) error
+	// Terminate the output. The underlying output is not closed.
+	Close() error
+}
+
+var WriterFormats = []string{"json", "ndjson", "csv", "tsv"}
+
+func NewWriter(format string, output io.Writer, keys []string, options ReaderOptions) (Writer, error) {
+	switch format {
+	case "json":
+		return NewJSONWriter(output, keys)
+	case "ndjson":
+		return NewNDJSONWriter(output, keys), nil
+	case "csv":
+		return NewCSVWriter(output, keys, options.CSVDelimiter)
+	case "tsv":
+		return NewTSVWriter(output, keys)
+	default:
+		return nil, fmt.Errorf("unknown dictionary data format '%s': expected one of %s", format, strings.Join(WriterFormats, ", "))
+	}
+}
+
+// Convert a value scanned from a column of the type into a value which
+// encodes to JSON the way the readers decode it: times are formatted as
+// ClickHouse does, decimals, UUIDs and IPs are strings.
+func (t *ColumnType) Export(value interface{}) interface{} {
+	reflected := reflect.ValueOf(value)
+
+	for reflected.IsValid() && reflected.Kind() == reflect.Pointer {
+		if reflected.IsNil() {
+			return nil
+		}
+
+		reflected = reflected.Elem()
+	}
+
+	if !reflected.IsValid() {
+		return nil
+	}
+
+	value = reflected.Interface()
+
+	switch t.Name {
+	case "Nullable", "LowCardinality":
+		return t.Elems[0].Export(value)
+	case "Array", "Tuple":
+		if reflected.Kind() != reflect.Slice && reflected.Kind() != reflect.Array {
+			return value
+		}
+
+		items := make([]interface{}, reflected.Len())
+
+		for i := range items {
+			elem := t.Elems[0]
+
+			if t.Name == "Tuple" && i < len(t.Elems) {
+				elem = t.Elems[i]
+			}
+
+			items[i] = elem.Export(reflected.Index(i).Interface())
+		}
+
+		return items
+	case "Map":
+		if reflected.Kind() != reflect.Map {
+			return value
+		}
+
+		items := make(map[string]interface{}, reflected.Len())
+		iterator := reflected.MapRange()
+
+		for iterator.Next() {
+			items[fmt.Sprint(t.Elems[0].Export(iterator.Key().Interface()))] = t.Elems[1].Export(iterator.Value().Interface())
+		}
+
+		return items
+	}
+
+	switch v := value.(type) {
+	case time.Time:
+		switch t.Name {
+		case "Date", "Date32":
+			return v.Format("2006-01-02")
+		case "DateTime64":
+			return v.Format("2006-01-02 15:04:05.999999999")
+		default:
+			return v.Format("2006-01-02 15:04:05")
+		}
+	case big.Int:
+		return json.Number(v.String())
+	case decimal.Decimal:
+		return v.String()
+	case uuid.UUID:
+		return v.String()
+	case net.IP:
+		return v.String()
+	default:
+		return value
+	}
+}
+
+// Writer of the `{"keys": [...], "values": [{...}, ...]}` format, with one
+// record per line so that exports can be reviewed and diffed.
+type JSONWriter struct {
+	output  *bufio.Writer
+	keys    []string
+	records int
+}
+
+func NewJSONWriter(output io.Writer, keys []string) (*JSONWriter, error) {
+	writer := &JSONWriter{output: bufio.NewWriter(output), keys: keys}
+
+	encodedKeys, err := marshalJSON(keys)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(writer.output, "{\"keys\": %s, \"values\": [", encodedKeys)
+
+	return writer, nil
+}
+
+func (w *JSONWriter) Write(values []interface{}) error {
+	record, err := marshalRecord(w.keys, values)
+
+	if err != nil {
+		return err
+	}
+
+	if w.records > 0 {
+		w.output.WriteString(",")
+	}
+
+	w.output.WriteString("\n  ")
+	w.output.Write(record)
+	w.records++
+
+	return nil
+}
+
+func (w *JSONWriter) Close() error {
+	w.output.WriteString("\n]}\n")
+
+	return w.output.Flush()
+}
+
+// Writer of newline-delimited JSON objects, keeping the order of the keys.
+type NDJSONWriter struct {
+	output *bufio.Writer
+	keys   []string
+}
+
+func NewNDJSONWriter(output io.Writer, keys []string) *NDJSONWriter {
+	return &NDJSONWriter{output: bufio.NewWriter(output), keys: keys}
+}
+
+func (w *NDJSONWriter) Write(values []interface{}) error {
+	record, err := marshalRecord(w.keys, values)
+
+	if err != nil {
+		return err
+	}
+
+	w.output.Write(record)
+	w.output.WriteString("\n")
+
+	return nil
+}
+
+func (w *NDJSONWriter) Close() error {
+	return w.output.Flush()
+}
+
+// Encode a record as a JSON object, with the keys in order.
+func marshalRecord(keys []string, values []interface{}) ([]byte, error) {
+	var builder strings.Builder
+
+	builder.WriteString("{")
+
+	for i, key := range keys {
+		encodedKey, err := marshalJSON(key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		encodedValue, err := marshalJSON(values[i])
+
+		if err != nil {
+			return nil, fmt.Errorf("column '%s': %s", key, err)
+		}
+
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+
+		builder.Write(encodedKey)
+		builder.WriteString(": ")
+		builder.Write(encodedValue)
+	}
+
+	builder.WriteString("}")
+
+	return []byte(builder.String()), nil
+}
+
+func marshalJSON(value interface{}) ([]byte, error) {
+	var builder strings.Builder
+
+	encoder := json.NewEncoder(&builder)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+
+	return []byte(strings.TrimSuffix(builder.String(), "\n")), nil
+}
+
+// Text of a value in CSV and TSV fields: nested values are encoded as JSON,
+// and nulls are `\N` as written by ClickHouse.
+func formatField(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return `\N`, nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case []interface{}, map[string]interface{}:
+		encoded, err := marshalJSON(v)
+
+		return string(encoded), err
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// Writer of CSV with a header line.
+type CSVWriter struct {
+	writer *csv.Writer
+}
+
+func NewCSVWriter(output io.Writer, keys []string, delimiter rune) (*CSVWriter, error) {
+	writer := csv.NewWriter(output)
+	writer.Comma = delimiter
+
+	if err := writer.Write(keys); err != nil {
+		return nil, err
+	}
+
+	return &CSVWriter{writer: writer}, nil
+}
+
+func (w *CSVWriter) Write(values []interface{}) error {
+	fields := make([]string, len(values))
+
+	for i, value := range values {
+		field, err := formatField(value)
+
+		if err != nil {
+			return err
+		}
+
+		fields[i] = field
+	}
+
+	return w.writer.Write(fields)
+}
+
+func (w *CSVWriter) Close() error {
+	w.writer.Flush()
+
+	return w.writer.Error()
+}
+
+// Writer of TSV with a header line, escaping fields the way ClickHouse's
+// TabSeparated format does.
+type TSVWriter struct {
+	output *bufio.Writer
+}
+
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r", "\x00", "\\0")
+
+func NewTSVWriter(output io.Writer, keys []string) (*TSVWriter, error) {
+	writer := &TSVWriter{output: bufio.NewWriter(output)}
+	header := make([]interface{}, len(keys))
+
+	for i, key := range keys {
+		header[i] = key
+	}
+
+	return writer, writer.Write(header)
+}
+
+func (w *TSVWriter) Write(values []interface{}) error {
+	for i, value := range values {
+		field, err := formatField(value)
+
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			w.output.WriteString("\t")
+		}
+
+		if value == nil {
+			w.output.WriteString(field)
+		} else {
+			w.output.WriteString(tsvEscaper.Replace(field))
+		}
+	}
+
+	_, err := w.output.WriteString("\n")
+
+	return err
+}
+
+func (w *TSVWriter) Close() error {
+	return w.output.Flush()
+}
@@ -0,0 +1,99 @@
+package dictionary_data
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriterRoundTrip(t *testing.T) {
+	keys := []string{"id", "name", "tags", "note", "updated_at"}
+	columnTypes := make([]*ColumnType, 0, len(keys))
+
+	// Times of columns without a timezone are scanned in the server timezone
+	timezone, err := time.LoadLocation("Europe/Paris")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, definition := range []string{"UInt64", "String", "Array(String)", "Nullable(String)", "DateTime"} {
+		columnType, err := ParseColumnType(definition)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		columnType.SetTimezone(timezone)
+		columnTypes = append(columnTypes, columnType)
+	}
+
+	rows := [][]interface{}{
+		{uint64(1), "Doe, \"John\"", []string{"a", "b"}, "first\tline\nsecond \\ line", time.Date(2024, 1, 2, 3, 4, 5, 0, timezone)},
+		{uint64(18446744073709551615), "", []string{}, nil, time.Date(1970, 1, 1, 1, 0, 0, 0, timezone)},
+	}
+
+	for _, format := range WriterFormats {
+		var output bytes.Buffer
+
+		writer, err := NewWriter(format, &output, keys, DefaultReaderOptions)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, row := range rows {
+			exported := make([]interface{}, len(row))
+
+			for i, value := range row {
+				exported[i] = columnTypes[i].Export(value)
+			}
+
+			if err := writer.Write(exported); err != nil {
+				t.Fatalf("%s: cannot write row: %s", format, err)
+			}
+		}
+
+		if err := writer.Close(); err != nil {
+			t.Fatalf("%s: cannot close writer: %s", format, err)
+		}
+
+		reader, err := NewReader(format, &output, DefaultReaderOptions)
+
+		if err != nil {
+			t.Fatalf("%s: cannot read written data: %s", format, err)
+		}
+
+		records := readAll(t, reader)
+
+		if len(records) != len(rows) {
+			t.Fatalf("%s: read %d records, expected %d", format, len(records), len(rows))
+		}
+
+		for i, record := range records {
+			for j, key := range keys {
+				value, err := columnTypes[j].Coerce(record[key])
+
+				if err != nil {
+					t.Errorf("%s: row %d, column %s: %s", format, i+1, key, err)
+					continue
+				}
+
+				expected := rows[i][j]
+
+				if expectedTime, ok := expected.(time.Time); ok {
+					if parsed, ok := value.(time.Time); !ok || !parsed.Equal(expectedTime) {
+						t.Errorf("%s: row %d, column %s = %#v, expected %s", format, i+1, key, value, expectedTime)
+					}
+
+					continue
+				}
+
+				if !reflect.DeepEqual(value, expected) {
+					t.Errorf("%s: row %d, column %s = %#v, expected %#v", format, i+1, key, value, expected)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package dictionary_sync
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// Outcome of the synchronization of one dictionary.
+type Result struct {
+	Options  Options
+	Rows     uint64
+	Duration time.Duration
+	Err      error
+}
+
+// Synchronize every given dictionary, running at most `parallelism` of them at
+// once over the connection pool. The connector is used for the dictionaries
+// synchronized on a cluster. Results are returned in the order of the
+// dictionaries.
+func SynchronizeAll(conn driver.Conn, connect Connector, dictionaries []Options, parallelism int) []Result {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	results := make([]Result, len(dictionaries))
+	semaphore := make(chan struct{}, parallelism)
+
+	var wg sync.WaitGroup
+
+	for i, options := range dictionaries {
+		wg.Add(1)
+
+		go func(i int, options Options) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			start := time.Now()
+			synchronizer := NewSynchronizer(conn, options)
+			synchronizer.SetConnector(connect)
+
+			rows, err := synchronizer.Synchronize()
+
+			results[i] = Result{
+				Options:  options,
+				Rows:     rows,
+				Duration: time.Since(start),
+				Err:      err,
+			}
+		}(i, options)
+	}
+
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,245 @@
+package dictionary_sync
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/clickhouse_wrapper"
+)
+
+// Versions of the backups are the UTC time at which they were taken, to the
+// millisecond, so that they sort chronologically. Backups taken by previous
+// releases are versioned to the second.
+const backupVersionLayout = "20060102150405.000"
+
+var backupVersionRegex = regexp.MustCompile(`^\d{14}(\d{3})?$`)
+
+// Version of a backup taken at the given time.
+func backupVersion(at time.Time) string {
+	return strings.Replace(at.UTC().Format(backupVersionLayout), ".", "", 1)
+}
+
+// Snapshot of the source table taken before a synchronization.
+type Backup struct {
+	Version string
+	Table   string
+}
+
+func backupTablePrefix(name string) string {
+	return fmt.Sprintf("%s_backup_", name)
+}
+
+// Backups of the source table, from the oldest to the most recent.
+func (s *Synchronizer) listBackups(sourceTable string) ([]Backup, error) {
+	database, name := clickhouse_wrapper.SplitTableName(sourceTable)
+	prefix := backupTablePrefix(name)
+
+	tables, err := clickhouse_wrapper.ListTables(s.conn, database, prefix)
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot list backups of %s: %s", sourceTable, err)
+	}
+
+	backups := make([]Backup, 0, len(tables))
+
+	for _, table := range tables {
+		version := strings.TrimPrefix(table, prefix)
+
+		if !backupVersionRegex.MatchString(version) {
+			continue
+		}
+
+		backups = append(backups, Backup{
+			Version: version,
+			Table:   clickhouse_wrapper.QualifiedTableName(database, table),
+		})
+	}
+
+	return backups, nil
+}
+
+// Snapshot the current content of the source table into a new backup table,
+// then drop the oldest backups beyond the number of backups to keep.
+func (s *Synchronizer) backupSourceTable(sourceTable string) error {
+	if s.options.Backups <= 0 {
+		return nil
+	}
+
+	if _, err := s.snapshotSourceTable(sourceTable); err != nil {
+		return err
+	}
+
+	backups, err := s.listBackups(sourceTable)
+
+	if err != nil {
+		return err
+	}
+
+	for len(backups) > s.options.Backups {
+		s.logger.Info(fmt.Sprintf("Dropping old backup: %s", backups[0].Table))
+
+		if err := clickhouse_wrapper.DropTable(s.conn, backups[0].Table); err != nil {
+			return fmt.Errorf("cannot drop old backup %s: %s", backups[0].Table, err)
+		}
+
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+// Copy the current content of the source table into a new backup table. The
+// version of the backup is moved past the versions of the existing backups
+// taken in the same millisecond, so that none of them is replaced.
+func (s *Synchronizer) snapshotSourceTable(sourceTable string) (Backup, error) {
+	database, name := clickhouse_wrapper.SplitTableName(sourceTable)
+
+	backups, err := s.listBackups(sourceTable)
+
+	if err != nil {
+		return Backup{}, err
+	}
+
+	existingVersions := make(map[string]bool, len(backups))
+
+	for _, backup := range backups {
+		existingVersions[backup.Version] = true
+	}
+
+	at := time.Now()
+
+	for existingVersions[backupVersion(at)] {
+		at = at.Add(time.Millisecond)
+	}
+
+	version := backupVersion(at)
+	backup := Backup{
+		Version: version,
+		Table:   clickhouse_wrapper.QualifiedTableName(database, backupTablePrefix(name)+version),
+	}
+
+	s.logger.Info(fmt.Sprintf("Backing up source table %s to %s", sourceTable, backup.Table))
+
+	if err := clickhouse_wrapper.CreateTableAs(s.conn, backup.Table, sourceTable); err != nil {
+		return backup, fmt.Errorf("cannot create backup table: %s", err)
+	}
+
+	if err := clickhouse_wrapper.CopyTableData(s.conn, backup.Table, sourceTable); err != nil {
+		clickhouse_wrapper.DropTable(s.conn, backup.Table)
+		return backup, fmt.Errorf("cannot back up source table: %s", err)
+	}
+
+	return backup, nil
+}
+
+// Restore the source table of the dictionary from a backup, the most recent
+// one when no version is given, and reload the dictionary. Backups are kept,
+// so that the same version can be restored again, and the data replaced by
+// the rollback is kept in a new backup, so that the rollback can be undone.
+// Returns the restored version.
+func (s *Synchronizer) Rollback(version string) (string, error) {
+	if s.options.DictionaryDatabase == "" || s.options.DictionaryName == "" {
+		return "", fmt.Errorf("no dictionary database or name provided")
+	}
+
+	// Backups and table swaps only exist on the connected host
+	if s.options.Cluster != "" {
+		return "", fmt.Errorf("rollback cannot be used on a cluster, roll back each host instead")
+	}
+
+	source, err := clickhouse_wrapper.GetDictionarySource(s.conn, s.options.DictionaryDatabase, s.options.DictionaryName)
+
+	if err != nil {
+		return "", fmt.Errorf("cannot get dictionary source: %s", err)
+	}
+
+	s.source = source
+
+	sourceTable, err := source.WritableTable()
+
+	if err != nil {
+		return "", fmt.Errorf("cannot roll back dictionary %s: %s", s.options.Dictionary(), err)
+	}
+
+	backups, err := s.listBackups(sourceTable)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(backups) == 0 {
+		return "", fmt.Errorf("source table %s has no backup", sourceTable)
+	}
+
+	backup := backups[len(backups)-1]
+
+	if version != "" {
+		versions := make([]string, 0, len(backups))
+		found := false
+
+		for _, candidate := range backups {
+			versions = append(versions, candidate.Version)
+
+			if candidate.Version == version {
+				backup = candidate
+				found = true
+			}
+		}
+
+		if !found {
+			return "", fmt.Errorf("no backup version %s of %s: available versions are %s", version, sourceTable, strings.Join(versions, ", "))
+		}
+	}
+
+	database, name := clickhouse_wrapper.SplitTableName(sourceTable)
+	stagingTable := clickhouse_wrapper.QualifiedTableName(database, name+"_staging")
+
+	s.logger.Info(fmt.Sprintf("Restoring source table %s from backup %s", sourceTable, backup.Table))
+
+	if err := clickhouse_wrapper.DropTable(s.conn, stagingTable); err != nil {
+		return "", fmt.Errorf("cannot drop previous staging table: %s", err)
+	}
+
+	if err := clickhouse_wrapper.CreateTableAs(s.conn, stagingTable, sourceTable); err != nil {
+		return "", fmt.Errorf("cannot create staging table: %s", err)
+	}
+
+	defer clickhouse_wrapper.DropTable(s.conn, stagingTable)
+
+	if err := clickhouse_wrapper.CopyTableData(s.conn, stagingTable, backup.Table); err != nil {
+		return "", fmt.Errorf("cannot copy backup to staging table: %s", err)
+	}
+
+	previous, err := s.snapshotSourceTable(sourceTable)
+
+	if err != nil {
+		return "", err
+	}
+
+	s.logger.Info(fmt.Sprintf("Data replaced by the rollback is kept in backup %s", previous.Version))
+
+	if err := clickhouse_wrapper.SwapTables(s.conn, sourceTable, stagingTable); err != nil {
+		return "", fmt.Errorf("cannot swap staging table with source table: %s", err)
+	}
+
+	s.logger.Info(fmt.Sprintf("Reloading dictionary: %s", s.options.DictionaryName))
+
+	if err := s.reloadDictionary(); err != nil {
+		return backup.Version, fmt.Errorf("cannot reload dictionary: %s", err)
+	}
+
+	// Tombstones of ReplacingMergeTree tables are counted until merged
+	if s.options.IsDeletedColumn != "" {
+		return backup.Version, s.verifyDictionaryLoaded(0, false)
+	}
+
+	count, err := clickhouse_wrapper.CountRowsWhere(s.conn, sourceTable, source.Where)
+
+	if err != nil {
+		return backup.Version, fmt.Errorf("cannot count rows of source table: %s", err)
+	}
+
+	return backup.Version, s.verifyDictionaryLoaded(count, true)
+}
@@ -0,0 +1,26 @@
+package dictionary_sync
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackupVersion(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 678900000, time.FixedZone("CET", 3600))
+
+	if version := backupVersion(at); version != "20240102020405678" {
+		t.Errorf("backupVersion = %s, expected 20240102020405678", version)
+	}
+
+	for _, version := range []string{"20240102020405678", "20240102020405"} {
+		if !backupVersionRegex.MatchString(version) {
+			t.Errorf("version %s is not recognized", version)
+		}
+	}
+
+	// Versions to the second of previous releases sort before the versions
+	// to the millisecond taken later
+	if !("20240102020405" < backupVersion(at) && backupVersion(at) < "20240102020406") {
+		t.Error("versions must sort chronologically")
+	}
+}
@@ -0,0 +1,170 @@
+package dictionary_sync
+
+import (
+	"fmt"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/clickhouse_wrapper"
+)
+
+// Connects to a host of the cluster, given as `host:port`.
+type Connector func(address string) (driver.Conn, error)
+
+// Set how to connect to the hosts of the cluster, needed to synchronize
+// source tables which are not replicated.
+func (s *Synchronizer) SetConnector(connect Connector) {
+	s.connect = connect
+}
+
+func (s *Synchronizer) reloadDictionary() error {
+	if s.options.Cluster != "" {
+		// Replicas must have fetched the written rows before reloading
+		if s.replicatedTable != "" {
+			s.logger.Info(fmt.Sprintf("Synchronizing replicas of source table on cluster %s: %s", s.options.Cluster, s.replicatedTable))
+
+			if err := clickhouse_wrapper.SyncReplicaOnCluster(s.conn, s.options.Cluster, s.replicatedTable); err != nil {
+				return fmt.Errorf("cannot synchronize replicas of %s: %s", s.replicatedTable, err)
+			}
+		}
+
+		return clickhouse_wrapper.ReloadDictionaryOnCluster(s.conn, s.options.Cluster, s.options.DictionaryDatabase, s.options.DictionaryName)
+	}
+
+	return clickhouse_wrapper.ReloadDictionary(s.conn, s.options.DictionaryDatabase, s.options.DictionaryName)
+}
+
+// Hosts of the cluster on which the source table must be written, none when
+// writing it on the connected host is enough. Replicated tables are written on
+// one replica of each shard, and local tables on every host.
+func (s *Synchronizer) clusterWriteHosts(sourceTable string) ([]clickhouse_wrapper.ClusterHost, error) {
+	engine, err := clickhouse_wrapper.GetTableEngine(s.conn, sourceTable)
+
+	if err != nil {
+		return nil, err
+	}
+
+	replicated := clickhouse_wrapper.IsReplicatedEngine(engine)
+
+	// Swapping tables only renames them on the connected host
+	if replicated && (s.options.Atomic || s.options.CleanupStrategy == clickhouse_wrapper.CleanupExchange) {
+		return nil, fmt.Errorf("tables cannot be exchanged on a cluster with the replicated source table %s", sourceTable)
+	}
+
+	hosts, err := clickhouse_wrapper.GetClusterHosts(s.conn, s.options.Cluster)
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot get hosts of cluster %s: %s", s.options.Cluster, err)
+	}
+
+	if !replicated {
+		return hosts, nil
+	}
+
+	s.replicatedTable = sourceTable
+
+	shards := make([]clickhouse_wrapper.ClusterHost, 0)
+	seenShards := make(map[uint32]bool)
+
+	for _, host := range hosts {
+		if !seenShards[host.ShardNum] {
+			seenShards[host.ShardNum] = true
+			shards = append(shards, host)
+		}
+	}
+
+	if len(shards) == 1 {
+		return nil, nil
+	}
+
+	return shards, nil
+}
+
+// Synchronize the source table on each of the given hosts of the cluster, over
+// a connection to each of them, then reload the dictionary on the cluster and
+// check that it holds the same data everywhere. Returns the number of rows
+// read from the source.
+func (s *Synchronizer) synchronizeClusterHosts(data Options, sourceTable string, hosts []clickhouse_wrapper.ClusterHost) (uint64, error) {
+	if s.connect == nil {
+		return 0, fmt.Errorf("source table %s must be written on several hosts of cluster %s, which cannot be connected to", sourceTable, s.options.Cluster)
+	}
+
+	// The data was validated already, and each host reloads its own
+	// dictionary
+	hostOptions := data
+	hostOptions.Cluster = ""
+	hostOptions.ValidateData = false
+	hostOptions.MinRows = 0
+	hostOptions.MaxChangePercent = 0
+
+	var inserted uint64
+
+	for _, host := range hosts {
+		s.logger.Info(fmt.Sprintf("Synchronizing source table %s on host: %s", sourceTable, host.Address()))
+
+		conn, err := s.connect(host.Address())
+
+		if err != nil {
+			return inserted, fmt.Errorf("cannot connect to %s: %s", host.Address(), err)
+		}
+
+		synchronizer := NewSynchronizer(conn, hostOptions)
+		synchronizer.logger = s.logger.With("host", host.Address())
+
+		inserted, err = synchronizer.Synchronize()
+		conn.Close()
+
+		if err != nil {
+			return inserted, fmt.Errorf("cannot synchronize host %s: %s", host.Address(), err)
+		}
+	}
+
+	// Replicas which were not written only see the data once reloaded
+	s.logger.Info(fmt.Sprintf("Reloading dictionary on cluster %s: %s", s.options.Cluster, s.options.DictionaryName))
+
+	if err := s.reloadDictionary(); err != nil {
+		return inserted, fmt.Errorf("cannot reload dictionary: %s", err)
+	}
+
+	return inserted, s.verifyDictionaryLoaded(0, false)
+}
+
+// Check that the dictionary is LOADED on every host of the cluster and, unless
+// disabled, that every host holds the expected number of elements, or the
+// same number of elements when it is not known.
+func (s *Synchronizer) verifyClusterDictionaryLoaded(expectedCount uint64, checkCount bool) error {
+	s.logger.Info(fmt.Sprintf("Verifying dictionary status on cluster %s: %s", s.options.Cluster, s.options.Dictionary()))
+
+	hosts, err := clickhouse_wrapper.GetClusterHosts(s.conn, s.options.Cluster)
+
+	if err != nil {
+		return fmt.Errorf("cannot get hosts of cluster %s: %s", s.options.Cluster, err)
+	}
+
+	statuses, err := clickhouse_wrapper.WaitForClusterDictionaryLoaded(s.conn, s.options.Cluster, s.options.DictionaryDatabase, s.options.DictionaryName, s.options.ReloadTimeout)
+
+	if err != nil {
+		return err
+	}
+
+	if len(statuses) != len(hosts) {
+		return fmt.Errorf("dictionary exists on %d of the %d hosts of cluster %s", len(statuses), len(hosts), s.options.Cluster)
+	}
+
+	// Tombstones of ReplacingMergeTree tables are counted until merged, which
+	// happens independently on each host
+	if !s.options.VerifyElementCount || (!checkCount && s.options.IsDeletedColumn != "") {
+		return nil
+	}
+
+	if !checkCount {
+		expectedCount = statuses[0].ElementCount
+	}
+
+	for _, status := range statuses {
+		if status.ElementCount != expectedCount {
+			return fmt.Errorf("dictionary holds %d elements on %s, expected %d", status.ElementCount, status.Host, expectedCount)
+		}
+	}
+
+	return nil
+}
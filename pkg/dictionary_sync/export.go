@@ -0,0 +1,110 @@
+package dictionary_sync
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/clickhouse_wrapper"
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/dictionary_data"
+)
+
+type ExportOptions struct {
+	DictionaryDatabase string
+	DictionaryName     string
+	// Read the content loaded in the dictionary, rather than its source table.
+	FromDictionary bool
+	Format         string
+	CSVDelimiter   rune
+}
+
+// Write the content of the source table of a dictionary, or of the dictionary
+// itself, in a format read by the synchronization. Records are sorted by the
+// dictionary key, so that exports of the same data are identical. Returns the
+// number of exported records.
+func Export(conn driver.Conn, options ExportOptions, output io.Writer) (uint64, error) {
+	keyNames, err := clickhouse_wrapper.GetDictionaryKeyNames(conn, options.DictionaryDatabase, options.DictionaryName)
+
+	if err != nil {
+		return 0, fmt.Errorf("cannot get dictionary key: %s", err)
+	}
+
+	var table string
+
+	if options.FromDictionary {
+		dictionary := fmt.Sprintf("%s.%s", options.DictionaryDatabase, options.DictionaryName)
+		table = fmt.Sprintf("dictionary('%s')", strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(dictionary))
+	} else {
+		source, err := clickhouse_wrapper.GetDictionarySource(conn, options.DictionaryDatabase, options.DictionaryName)
+
+		if err != nil {
+			return 0, fmt.Errorf("cannot get dictionary source: %s", err)
+		}
+
+		if table, err = source.WritableTable(); err != nil {
+			return 0, fmt.Errorf("cannot export source table: %s, export the dictionary instead", err)
+		}
+	}
+
+	orderBy := make([]string, len(keyNames))
+
+	for i, keyName := range keyNames {
+		orderBy[i] = clickhouse_wrapper.QuoteIdentifier(keyName)
+	}
+
+	query := fmt.Sprintf("SELECT * FROM %s ORDER BY %s", table, strings.Join(orderBy, ", "))
+
+	var writer dictionary_data.Writer
+	var columnTypes []*dictionary_data.ColumnType
+
+	readerOptions := dictionary_data.DefaultReaderOptions
+
+	if options.CSVDelimiter != 0 {
+		readerOptions.CSVDelimiter = options.CSVDelimiter
+	}
+
+	exported := uint64(0)
+
+	err = clickhouse_wrapper.StreamQuery(
+		conn,
+		query,
+		func(columns []clickhouse_wrapper.TableColumn) error {
+			keys := make([]string, len(columns))
+
+			for i, column := range columns {
+				columnType, err := dictionary_data.ParseColumnType(column.Type)
+
+				if err != nil {
+					return err
+				}
+
+				keys[i] = column.Name
+				columnTypes = append(columnTypes, columnType)
+			}
+
+			writer, err = dictionary_data.NewWriter(options.Format, output, keys, readerOptions)
+
+			return err
+		},
+		func(values []interface{}) error {
+			for i, value := range values {
+				values[i] = columnTypes[i].Export(value)
+			}
+
+			if err := writer.Write(values); err != nil {
+				return fmt.Errorf("row %d: %s", exported+1, err)
+			}
+
+			exported++
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return exported, err
+	}
+
+	return exported, writer.Close()
+}
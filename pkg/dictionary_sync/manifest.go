@@ -0,0 +1,95 @@
+package dictionary_sync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/dictionary_data"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest listing the dictionaries to synchronize. The options of each
+// dictionary are applied over the defaults of the manifest, using the same
+// keys as the `syncDictionary` flags:
+//
+//	defaults:
+//	  atomic: true
+//	dictionaries:
+//	  - dictionary-database: default
+//	    dictionary-name: countries
+//	    source: countries.csv
+type Manifest struct {
+	Defaults     map[string]interface{}   `yaml:"defaults"`
+	Dictionaries []map[string]interface{} `yaml:"dictionaries"`
+}
+
+// Read the manifest at the given path, and resolve the options of each of its
+// dictionaries over the given base options. Relative sources are resolved
+// against the directory of the manifest.
+func LoadManifest(path string, base Options) ([]Options, error) {
+	content, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+
+	if err := yaml.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("cannot parse manifest %s: %s", path, err)
+	}
+
+	if len(manifest.Dictionaries) == 0 {
+		return nil, fmt.Errorf("manifest %s lists no dictionaries", path)
+	}
+
+	if err := decodeOptions(manifest.Defaults, &base); err != nil {
+		return nil, fmt.Errorf("invalid defaults in manifest %s: %s", path, err)
+	}
+
+	manifestDirectory := filepath.Dir(path)
+	dictionaries := make([]Options, 0, len(manifest.Dictionaries))
+
+	for i, values := range manifest.Dictionaries {
+		options := base
+
+		if err := decodeOptions(values, &options); err != nil {
+			return nil, fmt.Errorf("invalid dictionary #%d in manifest %s: %s", i+1, path, err)
+		}
+
+		// Only local files are relative to the manifest, URLs and SQL sources
+		// are kept as is
+		if options.Source != "" && options.Source != "-" && !dictionary_data.IsRemoteSource(options.Source) && !filepath.IsAbs(options.Source) {
+			options.Source = filepath.Join(manifestDirectory, options.Source)
+		}
+
+		if err := options.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid dictionary #%d in manifest %s: %s", i+1, path, err)
+		}
+
+		dictionaries = append(dictionaries, options)
+	}
+
+	return dictionaries, nil
+}
+
+func decodeOptions(values map[string]interface{}, options *Options) error {
+	if values == nil {
+		return nil
+	}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		WeaklyTypedInput: true,
+		ErrorUnused:      true,
+		Result:           options,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return decoder.Decode(values)
+}
@@ -0,0 +1,97 @@
+package dictionary_sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadManifest(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "dictionaries.yaml")
+
+	manifest := `
+defaults:
+  atomic: true
+  reload-timeout: 30s
+dictionaries:
+  - dictionary-database: default
+    dictionary-name: countries
+    source: data/countries.csv
+  - dictionary-database: default
+    dictionary-name: currencies
+    source: s3://bucket/currencies.ndjson
+  - dictionary-database: default
+    dictionary-name: rates
+    source: https://example.com/rates.json
+    atomic: false
+  - dictionary-database: default
+    dictionary-name: users
+    from-sql: sqlite://reference.db
+    query: SELECT * FROM users
+`
+
+	if err := os.WriteFile(path, []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dictionaries, err := LoadManifest(path, DefaultOptions)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dictionaries) != 4 {
+		t.Fatalf("loaded %d dictionaries, expected 4", len(dictionaries))
+	}
+
+	expectedSources := []string{
+		filepath.Join(directory, "data/countries.csv"),
+		"s3://bucket/currencies.ndjson",
+		"https://example.com/rates.json",
+		"",
+	}
+
+	for i, expected := range expectedSources {
+		if dictionaries[i].Source != expected {
+			t.Errorf("source of dictionary #%d = %s, expected %s", i+1, dictionaries[i].Source, expected)
+		}
+	}
+
+	if dictionaries[3].FromSQL != "sqlite://reference.db" {
+		t.Errorf("SQL source = %s, expected it unchanged", dictionaries[3].FromSQL)
+	}
+
+	if !dictionaries[0].Atomic || dictionaries[2].Atomic {
+		t.Error("defaults must apply unless overridden by the dictionary")
+	}
+
+	if dictionaries[0].ReloadTimeout != 30*time.Second {
+		t.Errorf("reload timeout = %s, expected 30s", dictionaries[0].ReloadTimeout)
+	}
+
+	if dictionaries[0].BatchSize != DefaultOptions.BatchSize {
+		t.Errorf("batch size = %d, expected the default %d", dictionaries[0].BatchSize, DefaultOptions.BatchSize)
+	}
+}
+
+func TestLoadManifestUnknownOption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dictionaries.yaml")
+
+	manifest := `
+dictionaries:
+  - dictionary-database: default
+    dictionary-name: countries
+    source: countries.csv
+    atomik: true
+`
+
+	if err := os.WriteFile(path, []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadManifest(path, DefaultOptions); err == nil {
+		t.Error("expected an error for an unknown option")
+	}
+}
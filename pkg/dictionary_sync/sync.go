@@ -0,0 +1,1037 @@
+package dictionary_sync
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/clickhouse_wrapper"
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/dictionary_data"
+)
+
+// Options of the synchronization of a dictionary. The mapstructure tags are
+// the keys of the `syncDictionary` flags, and of the dictionaries manifest.
+type Options struct {
+	DictionaryDatabase string `mapstructure:"dictionary-database"`
+	DictionaryName     string `mapstructure:"dictionary-name"`
+	// Path of the dictionary data, `-` for stdin.
+	Source       string `mapstructure:"source"`
+	Format       string `mapstructure:"format"`
+	CSVDelimiter string `mapstructure:"csv-delimiter"`
+	CSVQuote     string `mapstructure:"csv-quote"`
+	BatchSize    int    `mapstructure:"batch-size"`
+	BatchBytes   int    `mapstructure:"batch-bytes"`
+
+	// SQL database read instead of the source, as `driver://dsn`, and the
+	// query returning the dictionary data.
+	FromSQL string `mapstructure:"from-sql"`
+	Query   string `mapstructure:"query"`
+
+	// Fetching of HTTP(S) and S3 sources.
+	Headers           []string `mapstructure:"headers"`
+	BearerToken       string   `mapstructure:"bearer-token"`
+	HTTPCache         bool     `mapstructure:"http-cache"`
+	HTTPCacheDir      string   `mapstructure:"http-cache-dir"`
+	S3Endpoint        string   `mapstructure:"s3-endpoint"`
+	S3Region          string   `mapstructure:"s3-region"`
+	S3AccessKeyID     string   `mapstructure:"s3-access-key-id"`
+	S3SecretAccessKey string   `mapstructure:"s3-secret-access-key"`
+	S3SessionToken    string   `mapstructure:"s3-session-token"`
+
+	Atomic          bool   `mapstructure:"atomic"`
+	Incremental     bool   `mapstructure:"incremental"`
+	IsDeletedColumn string `mapstructure:"is-deleted-column"`
+
+	// Mapping of the fields of the data to the columns of the source table.
+	Columns         map[string]dictionary_data.ColumnMapping `mapstructure:"columns"`
+	DropExtraFields bool                                     `mapstructure:"drop-extra-fields"`
+
+	// Validation of the data before it is written.
+	ValidateData     bool    `mapstructure:"validate"`
+	NullAsDefault    bool    `mapstructure:"null-as-default"`
+	MinRows          uint64  `mapstructure:"min-rows"`
+	MaxChangePercent float64 `mapstructure:"max-change-percent"`
+
+	// How the previous data of the source table is removed, see the
+	// clickhouse_wrapper cleanup strategies.
+	CleanupStrategy string `mapstructure:"cleanup-strategy"`
+
+	// Number of snapshots of the source table to keep, 0 to disable them. Each
+	// snapshot is a full copy of the source table.
+	Backups int `mapstructure:"backups"`
+
+	// Cluster on which the dictionary is reloaded and verified. Source tables
+	// which are not replicated are written on every host of the cluster, and
+	// replicated ones on one replica of each shard.
+	Cluster string `mapstructure:"cluster"`
+
+	Verify             bool          `mapstructure:"verify"`
+	VerifyElementCount bool          `mapstructure:"verify-element-count"`
+	ReloadTimeout      time.Duration `mapstructure:"reload-timeout"`
+}
+
+// Defaults of the options, shared by the flags and the dictionaries manifest.
+var DefaultOptions = Options{
+	CSVDelimiter:       ",",
+	CSVQuote:           "\"",
+	BatchSize:          100000,
+	BatchBytes:         64 * 1024 * 1024,
+	HTTPCache:          true,
+	CleanupStrategy:    clickhouse_wrapper.CleanupAuto,
+	Backups:            3,
+	ValidateData:       true,
+	Verify:             true,
+	VerifyElementCount: true,
+	ReloadTimeout:      time.Minute,
+}
+
+// Name of the dictionary, as `database.name`.
+func (o Options) Dictionary() string {
+	return fmt.Sprintf("%s.%s", o.DictionaryDatabase, o.DictionaryName)
+}
+
+func (o Options) Validate() error {
+	if o.Source == "" && o.FromSQL == "" {
+		return fmt.Errorf("no source provided")
+	}
+
+	if o.Source != "" && o.FromSQL != "" {
+		return fmt.Errorf("a source and a SQL source cannot be combined")
+	}
+
+	if o.FromSQL != "" && o.Query == "" {
+		return fmt.Errorf("no query provided for the SQL source")
+	}
+
+	if o.DictionaryDatabase == "" || o.DictionaryName == "" {
+		return fmt.Errorf("no dictionary database or name provided")
+	}
+
+	if !o.ValidateData && (o.MinRows > 0 || o.MaxChangePercent > 0) {
+		return fmt.Errorf("the minimum rows and maximum change percent are only checked when validating the data")
+	}
+
+	if o.Atomic && o.Incremental {
+		return fmt.Errorf("the atomic and incremental modes cannot be combined")
+	}
+
+	if o.CleanupStrategy != "" {
+		if err := clickhouse_wrapper.ValidateCleanupStrategy(o.CleanupStrategy); err != nil {
+			return err
+		}
+
+		if (o.Atomic || o.Incremental) && o.CleanupStrategy != clickhouse_wrapper.CleanupAuto {
+			return fmt.Errorf("the cleanup strategy cannot be combined with the atomic and incremental modes")
+		}
+	}
+
+	return nil
+}
+
+func (o Options) readerOptions() (dictionary_data.ReaderOptions, error) {
+	options := dictionary_data.DefaultReaderOptions
+
+	delimiter := []rune(o.CSVDelimiter)
+
+	if len(delimiter) != 1 {
+		return options, fmt.Errorf("CSV delimiter must be a single character")
+	}
+
+	options.CSVDelimiter = delimiter[0]
+
+	quote := []rune(o.CSVQuote)
+
+	switch len(quote) {
+	case 0:
+		options.CSVQuote = 0
+	case 1:
+		options.CSVQuote = quote[0]
+	default:
+		return options, fmt.Errorf("CSV quote must be a single character, or empty to disable quoting")
+	}
+
+	return options, nil
+}
+
+func (o Options) sourceOptions() dictionary_data.SourceOptions {
+	options := dictionary_data.SourceOptions{
+		Headers:           o.Headers,
+		BearerToken:       o.BearerToken,
+		S3Endpoint:        o.S3Endpoint,
+		S3Region:          o.S3Region,
+		S3AccessKeyID:     o.S3AccessKeyID,
+		S3SecretAccessKey: o.S3SecretAccessKey,
+		S3SessionToken:    o.S3SessionToken,
+	}
+
+	if o.HTTPCache {
+		options.CacheDirectory = o.HTTPCacheDir
+
+		if options.CacheDirectory == "" {
+			options.CacheDirectory = dictionary_data.DefaultCacheDirectory()
+		}
+	}
+
+	return options
+}
+
+// Open the source of the dictionary data, without decoding it.
+func (o Options) OpenRawSource() (io.ReadCloser, error) {
+	return dictionary_data.Open(o.Source, o.sourceOptions())
+}
+
+// Open the source of the dictionary data and a reader of its records. The
+// rows of SQL sources are read as the query returns them.
+func (o Options) OpenSource() (io.ReadCloser, dictionary_data.Reader, error) {
+	if o.FromSQL != "" {
+		reader, err := dictionary_data.OpenSQL(o.FromSQL, o.Query)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return sqlInput{reader}, reader, nil
+	}
+
+	input, err := o.OpenRawSource()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	format := o.Format
+
+	if format == "" {
+		format = dictionary_data.DetectFormat(o.Source)
+	}
+
+	readerOptions, err := o.readerOptions()
+
+	if err != nil {
+		input.Close()
+		return nil, nil, err
+	}
+
+	reader, err := dictionary_data.NewReader(format, input, readerOptions)
+
+	if err != nil {
+		input.Close()
+		return nil, nil, err
+	}
+
+	return input, reader, nil
+}
+
+// Input of SQL sources, whose rows are not read as bytes. Closing it closes
+// the connection to the database.
+type sqlInput struct {
+	io.Closer
+}
+
+func (sqlInput) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
+
+// Synchronizes one dictionary over a shared connection.
+type Synchronizer struct {
+	conn    driver.Conn
+	options Options
+	logger  *slog.Logger
+	// Source of the dictionary, resolved when synchronizing.
+	source *clickhouse_wrapper.DictionarySource
+	// Connects to the hosts of the cluster, to write local source tables.
+	connect Connector
+	// Replicated source table written on a cluster, synchronized on every
+	// replica before the dictionary is reloaded.
+	replicatedTable string
+}
+
+func NewSynchronizer(conn driver.Conn, options Options) *Synchronizer {
+	return &Synchronizer{
+		conn:    conn,
+		options: options,
+		logger:  slog.With("dictionary", options.Dictionary()),
+	}
+}
+
+// Synchronize the dictionary from its source. Returns the number of rows read
+// from the source.
+func (s *Synchronizer) Synchronize() (uint64, error) {
+	if err := s.options.Validate(); err != nil {
+		return 0, err
+	}
+
+	data, cleanup, err := s.prepareData(false)
+
+	if err != nil {
+		return 0, fmt.Errorf("cannot read dictionnary data: %s", err)
+	}
+
+	defer cleanup()
+
+	return s.synchronizeData(data)
+}
+
+// Synchronize the dictionary from the prepared dictionary data. Returns the
+// number of rows read from the data.
+func (s *Synchronizer) synchronizeData(data Options) (uint64, error) {
+	s.logger.Info(fmt.Sprintf("Initial reload of dictionary: %s", s.options.Dictionary()))
+
+	if err := s.reloadDictionary(); err != nil {
+		return 0, fmt.Errorf("cannot reload dictionary: %s", err)
+	}
+
+	sourceTable, err := s.resolveSourceTable()
+
+	if err != nil {
+		return 0, err
+	}
+
+	s.logger.Info(fmt.Sprintf("Dictionary source table is: %s", sourceTable))
+
+	// Source tables of a cluster may have to be written on several hosts
+	var writeHosts []clickhouse_wrapper.ClusterHost
+
+	if s.options.Cluster != "" {
+		if writeHosts, err = s.clusterWriteHosts(sourceTable); err != nil {
+			return 0, err
+		}
+	}
+
+	if s.options.ValidateData {
+		report, err := s.validateSource(data, sourceTable)
+
+		if err != nil {
+			return 0, err
+		}
+
+		if err := report.Err(); err != nil {
+			return 0, fmt.Errorf("invalid dictionary data: %s", err)
+		}
+	}
+
+	if len(writeHosts) > 0 {
+		return s.synchronizeClusterHosts(data, sourceTable, writeHosts)
+	}
+
+	input, reader, err := s.openData(data, sourceTable)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer input.Close()
+
+	switch {
+	case s.options.Atomic:
+		return s.atomicSyncSourceTable(sourceTable, reader)
+	case s.options.Incremental:
+		return s.incrementalSyncSourceTable(sourceTable, reader)
+	}
+
+	strategy, err := s.cleanupStrategy(sourceTable)
+
+	if err != nil {
+		return 0, err
+	}
+
+	switch strategy {
+	case clickhouse_wrapper.CleanupExchange:
+		return s.atomicSyncSourceTable(sourceTable, reader)
+	case clickhouse_wrapper.CleanupPartitionReplace:
+		return s.replacePartitionsSyncSourceTable(sourceTable, reader)
+	}
+
+	if err := s.backupSourceTable(sourceTable); err != nil {
+		return 0, err
+	}
+
+	s.logger.Info(fmt.Sprintf("Cleaning up source table %s with strategy: %s", sourceTable, strategy))
+
+	if err := clickhouse_wrapper.CleanupTable(s.conn, sourceTable, strategy); err != nil {
+		return 0, fmt.Errorf("cannot clean up source table: %s", err)
+	}
+
+	s.logger.Info(fmt.Sprintf("Inserting data in source table: %s", sourceTable))
+
+	inserted, err := s.insertDataInTable(sourceTable, reader)
+
+	if err != nil {
+		return inserted, fmt.Errorf("cannot insert data in source table after %d rows: %s", inserted, err)
+	}
+
+	s.logger.Info(fmt.Sprintf("Inserted %d rows in source table: %s", inserted, sourceTable))
+
+	s.logger.Info(fmt.Sprintf("Reloading dictionary: %s", s.options.DictionaryName))
+
+	if err := s.reloadDictionary(); err != nil {
+		return inserted, fmt.Errorf("cannot reload dictionary: %s", err)
+	}
+
+	count, err := s.countDictionaryRows(sourceTable, inserted)
+
+	if err != nil {
+		return inserted, err
+	}
+
+	return inserted, s.verifyDictionaryLoaded(count, true)
+}
+
+// Number of rows of the source table read by the dictionary. Only the rows
+// matching the WHERE clause of the source are read, otherwise every row of
+// the table.
+func (s *Synchronizer) countDictionaryRows(sourceTable string, inserted uint64) (uint64, error) {
+	if s.source.Where == "" {
+		return inserted, nil
+	}
+
+	count, err := clickhouse_wrapper.CountRowsWhere(s.conn, sourceTable, s.source.Where)
+
+	if err != nil {
+		return 0, fmt.Errorf("cannot count rows of source table: %s", err)
+	}
+
+	return count, nil
+}
+
+// Validate the dictionary data without writing anything. The source table and
+// the dictionary key are read to check the data against them.
+func (s *Synchronizer) Check() (*ValidationReport, error) {
+	if err := s.options.Validate(); err != nil {
+		return nil, err
+	}
+
+	sourceTable, err := s.resolveSourceTable()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.validateSource(s.options, sourceTable)
+}
+
+func (s *Synchronizer) resolveSourceTable() (string, error) {
+	source, err := clickhouse_wrapper.GetDictionarySource(s.conn, s.options.DictionaryDatabase, s.options.DictionaryName)
+
+	if err != nil {
+		return "", fmt.Errorf("cannot get dictionary source: %s", err)
+	}
+
+	s.source = source
+
+	sourceTable, err := source.WritableTable()
+
+	if err != nil {
+		return "", fmt.Errorf("cannot synchronize dictionary %s: %s", s.options.Dictionary(), err)
+	}
+
+	return sourceTable, nil
+}
+
+// Options reading the dictionary data. The data is read once to be validated
+// before being inserted, and once per host of the cluster, so sources which
+// cannot be read twice, or could change between reads (stdin, URLs and SQL
+// queries), are first spooled to a temporary file, removed by the returned
+// cleanup function. Every source is spooled when spoolAlways is set.
+func (s *Synchronizer) prepareData(spoolAlways bool) (Options, func(), error) {
+	if s.options.FromSQL != "" {
+		s.logger.Info(fmt.Sprintf("Reading dictionnary data from SQL database: %s", dictionary_data.RedactSQLSource(s.options.FromSQL)))
+
+		if !spoolAlways && !s.options.ValidateData && s.options.Cluster == "" {
+			return s.options, func() {}, nil
+		}
+
+		return s.spoolSQLData()
+	}
+
+	if s.options.Source == "-" {
+		s.logger.Info("Reading dictionnary data from stdin")
+	} else {
+		s.logger.Info(fmt.Sprintf("Reading dictionnary data from: %s", s.options.Source))
+	}
+
+	readOnce := !s.options.ValidateData && s.options.Cluster == ""
+	isLocalFile := s.options.Source != "-" && !dictionary_data.IsRemoteSource(s.options.Source)
+
+	if !spoolAlways && (readOnce || isLocalFile) {
+		return s.options, func() {}, nil
+	}
+
+	input, err := s.options.OpenRawSource()
+
+	if err != nil {
+		return s.options, nil, err
+	}
+
+	defer input.Close()
+
+	spool, err := os.CreateTemp("", "clickhouse-toolbox-*")
+
+	if err != nil {
+		return s.options, nil, err
+	}
+
+	defer spool.Close()
+
+	cleanup := func() { os.Remove(spool.Name()) }
+
+	if _, err := io.Copy(spool, input); err != nil {
+		cleanup()
+		return s.options, nil, err
+	}
+
+	data := s.options
+	data.Source = spool.Name()
+
+	if data.Format == "" {
+		data.Format = dictionary_data.DetectFormat(s.options.Source)
+	}
+
+	return data, cleanup, nil
+}
+
+// Run the query of the SQL source once, and write the rows it returns to a
+// temporary NDJSON file, so that every read of the data sees the same rows.
+func (s *Synchronizer) spoolSQLData() (Options, func(), error) {
+	input, reader, err := s.options.OpenSource()
+
+	if err != nil {
+		return s.options, nil, err
+	}
+
+	defer input.Close()
+
+	spool, err := os.CreateTemp("", "clickhouse-toolbox-*.ndjson")
+
+	if err != nil {
+		return s.options, nil, err
+	}
+
+	defer spool.Close()
+
+	cleanup := func() { os.Remove(spool.Name()) }
+	keys := reader.Keys()
+	writer := dictionary_data.NewNDJSONWriter(spool, keys)
+
+	for {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err == nil {
+			values := make([]interface{}, len(keys))
+
+			for i, key := range keys {
+				values[i] = record[key]
+			}
+
+			err = writer.Write(values)
+		}
+
+		if err != nil {
+			cleanup()
+			return s.options, nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		cleanup()
+		return s.options, nil, err
+	}
+
+	data := s.options
+	data.FromSQL = ""
+	data.Query = ""
+	data.Source = spool.Name()
+	data.Format = "ndjson"
+
+	return data, cleanup, nil
+}
+
+// Open the dictionary data, mapping its fields to the columns of the source
+// table.
+func (s *Synchronizer) openData(data Options, sourceTable string) (io.ReadCloser, dictionary_data.Reader, error) {
+	input, reader, err := data.OpenSource()
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read dictionnary data: %s", err)
+	}
+
+	mapping := dictionary_data.MappingOptions{
+		Columns:         s.options.Columns,
+		DropExtraFields: s.options.DropExtraFields,
+	}
+
+	if mapping.IsEmpty() {
+		return input, reader, nil
+	}
+
+	if mapping.DropExtraFields {
+		tableColumns, err := clickhouse_wrapper.GetTableColumns(s.conn, sourceTable)
+
+		if err != nil {
+			input.Close()
+			return nil, nil, fmt.Errorf("cannot read columns of %s: %s", sourceTable, err)
+		}
+
+		for _, column := range tableColumns {
+			if column.DefaultKind == "" || column.DefaultKind == "DEFAULT" {
+				mapping.TableColumns = append(mapping.TableColumns, column.Name)
+			}
+		}
+	}
+
+	mapped, err := dictionary_data.NewMappedReader(reader, mapping)
+
+	if err != nil {
+		input.Close()
+		return nil, nil, fmt.Errorf("invalid column mapping: %s", err)
+	}
+
+	return input, mapped, nil
+}
+
+func (s *Synchronizer) validateSource(data Options, sourceTable string) (*ValidationReport, error) {
+	s.logger.Info("Validating dictionary data")
+
+	input, reader, err := s.openData(data, sourceTable)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer input.Close()
+
+	return s.validateData(sourceTable, reader)
+}
+
+// Insert the records of the reader in the given table, sending a batch every
+// time the batch size or the batch bytes limit is reached. Returns the number
+// of inserted rows.
+func (s *Synchronizer) insertDataInTable(table string, reader dictionary_data.Reader) (uint64, error) {
+	keys := reader.Keys()
+
+	missing, err := clickhouse_wrapper.ValidateInsertColumns(s.conn, table, keys, s.optionalColumns()...)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if len(missing) > 0 {
+		s.logger.Warn(fmt.Sprintf("Columns missing from the data are filled with the default value of their type: %s", strings.Join(missing, ", ")))
+	}
+
+	converter, err := s.newValueConverter(table)
+
+	if err != nil {
+		return 0, err
+	}
+
+	rows := make([][]interface{}, 0)
+	rowsBytes := 0
+	inserted := uint64(0)
+
+	flush := func() error {
+		if len(rows) == 0 {
+			return nil
+		}
+
+		s.logger.Debug("Sending batch", "rows", len(rows), "bytes", rowsBytes)
+
+		if err := clickhouse_wrapper.BatchInsertDataInTable(s.conn, table, keys, rows); err != nil {
+			return err
+		}
+
+		inserted += uint64(len(rows))
+		rows = make([][]interface{}, 0)
+		rowsBytes = 0
+
+		return nil
+	}
+
+	for rowNumber := 1; ; rowNumber++ {
+		values, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return inserted, fmt.Errorf("row %d: %s", rowNumber, err)
+		}
+
+		data := make([]interface{}, 0, len(keys))
+
+		for _, key := range keys {
+			value, ok := values[key]
+
+			if !ok {
+				return inserted, fmt.Errorf("row %d: missing key '%s' in values", rowNumber, key)
+			}
+
+			value, err := converter.convert(key, value)
+
+			if err != nil {
+				return inserted, fmt.Errorf("row %d, column '%s': %s", rowNumber, key, err)
+			}
+
+			data = append(data, value)
+			rowsBytes += dictionary_data.ApproximateSize(value)
+		}
+
+		rows = append(rows, data)
+
+		if (s.options.BatchSize > 0 && len(rows) >= s.options.BatchSize) || (s.options.BatchBytes > 0 && rowsBytes >= s.options.BatchBytes) {
+			if err := flush(); err != nil {
+				return inserted, err
+			}
+		}
+	}
+
+	return inserted, flush()
+}
+
+// Columns which the data may omit although they have no default: the
+// is_deleted column is only written by incremental synchronizations.
+func (s *Synchronizer) optionalColumns() []string {
+	if s.options.IsDeletedColumn == "" {
+		return nil
+	}
+
+	return []string{s.options.IsDeletedColumn}
+}
+
+// Converts the values of the dictionary data to the types of the columns of a
+// table.
+type valueConverter struct {
+	columnTypes   map[string]*dictionary_data.ColumnType
+	nullAsDefault bool
+}
+
+func (s *Synchronizer) newValueConverter(table string) (*valueConverter, error) {
+	tableColumns, err := clickhouse_wrapper.GetTableColumns(s.conn, table)
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot read columns of %s: %s", table, err)
+	}
+
+	timezone, err := clickhouse_wrapper.GetServerTimezone(s.conn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	columnTypes := make(map[string]*dictionary_data.ColumnType)
+
+	for _, column := range tableColumns {
+		columnType, err := dictionary_data.ParseColumnType(column.Type)
+
+		if err != nil {
+			return nil, err
+		}
+
+		columnType.SetTimezone(timezone)
+		columnTypes[column.Name] = columnType
+	}
+
+	return &valueConverter{columnTypes: columnTypes, nullAsDefault: s.options.NullAsDefault}, nil
+}
+
+// Convert the value of a column, replacing nulls of non-Nullable columns by
+// the column default when enabled. Values of unknown columns are kept as is.
+func (c *valueConverter) convert(column string, value interface{}) (interface{}, error) {
+	columnType, ok := c.columnTypes[column]
+
+	if !ok {
+		return value, nil
+	}
+
+	if c.nullAsDefault && (value == nil || value == `\N`) {
+		return columnType.DefaultValue()
+	}
+
+	return columnType.Coerce(value)
+}
+
+// Check that the dictionary loaded the new data: its status must be LOADED
+// without exception and, unless disabled, it must hold the expected number of
+// elements.
+func (s *Synchronizer) verifyDictionaryLoaded(expectedCount uint64, checkCount bool) error {
+	if !s.options.Verify {
+		return nil
+	}
+
+	if s.options.Cluster != "" {
+		return s.verifyClusterDictionaryLoaded(expectedCount, checkCount)
+	}
+
+	s.logger.Info(fmt.Sprintf("Verifying dictionary status: %s", s.options.Dictionary()))
+
+	status, err := clickhouse_wrapper.WaitForDictionaryLoaded(s.conn, s.options.DictionaryDatabase, s.options.DictionaryName, s.options.ReloadTimeout)
+
+	if err != nil {
+		return err
+	}
+
+	if checkCount && s.options.VerifyElementCount && status.ElementCount != expectedCount {
+		return fmt.Errorf("dictionary holds %d elements, expected %d", status.ElementCount, expectedCount)
+	}
+
+	return nil
+}
+
+// Create a copy of the source table and insert the data in it. The staging
+// table is dropped if the data cannot be fully loaded.
+func (s *Synchronizer) loadStagingTable(sourceTable string, reader dictionary_data.Reader) (string, uint64, error) {
+	database, name := clickhouse_wrapper.SplitTableName(sourceTable)
+	stagingTable := clickhouse_wrapper.QualifiedTableName(database, name+"_staging")
+
+	s.logger.Info(fmt.Sprintf("Creating staging table: %s", stagingTable))
+
+	if err := clickhouse_wrapper.DropTable(s.conn, stagingTable); err != nil {
+		return "", 0, fmt.Errorf("cannot drop previous staging table: %s", err)
+	}
+
+	if err := clickhouse_wrapper.CreateTableAs(s.conn, stagingTable, sourceTable); err != nil {
+		return "", 0, fmt.Errorf("cannot create staging table: %s", err)
+	}
+
+	s.logger.Info(fmt.Sprintf("Inserting data in staging table: %s", stagingTable))
+
+	inserted, err := s.insertDataInTable(stagingTable, reader)
+
+	if err != nil {
+		clickhouse_wrapper.DropTable(s.conn, stagingTable)
+		return "", inserted, fmt.Errorf("cannot insert data in staging table: %s", err)
+	}
+
+	count, err := clickhouse_wrapper.CountRows(s.conn, stagingTable)
+
+	if err != nil {
+		clickhouse_wrapper.DropTable(s.conn, stagingTable)
+		return "", inserted, fmt.Errorf("cannot count rows of staging table: %s", err)
+	}
+
+	if count != inserted {
+		clickhouse_wrapper.DropTable(s.conn, stagingTable)
+		return "", inserted, fmt.Errorf("staging table contains %d rows, expected %d", count, inserted)
+	}
+
+	return stagingTable, inserted, nil
+}
+
+// Load the data into a staging copy of the source table, then swap it with
+// the source table so that the dictionary never sees a partially filled
+// source. The previous data is only dropped once the dictionary reloaded.
+func (s *Synchronizer) atomicSyncSourceTable(sourceTable string, reader dictionary_data.Reader) (uint64, error) {
+	stagingTable, inserted, err := s.loadStagingTable(sourceTable, reader)
+
+	if err != nil {
+		return inserted, err
+	}
+
+	if err := s.backupSourceTable(sourceTable); err != nil {
+		clickhouse_wrapper.DropTable(s.conn, stagingTable)
+		return inserted, err
+	}
+
+	s.logger.Info(fmt.Sprintf("Swapping staging table %s with source table %s", stagingTable, sourceTable))
+
+	if err := clickhouse_wrapper.SwapTables(s.conn, sourceTable, stagingTable); err != nil {
+		clickhouse_wrapper.DropTable(s.conn, stagingTable)
+		return inserted, fmt.Errorf("cannot swap staging table with source table: %s", err)
+	}
+
+	s.logger.Info(fmt.Sprintf("Reloading dictionary: %s", s.options.DictionaryName))
+
+	err = s.reloadDictionary()
+
+	if err == nil {
+		var count uint64
+
+		if count, err = s.countDictionaryRows(sourceTable, inserted); err == nil {
+			err = s.verifyDictionaryLoaded(count, true)
+		}
+	}
+
+	if err != nil {
+		s.logger.Warn("Dictionary reload failed, restoring previous source table data")
+
+		if swapErr := clickhouse_wrapper.SwapTables(s.conn, sourceTable, stagingTable); swapErr != nil {
+			return inserted, fmt.Errorf("cannot reload dictionary: %s, and cannot restore previous data (kept in %s): %s", err, stagingTable, swapErr)
+		}
+
+		s.reloadDictionary()
+		clickhouse_wrapper.DropTable(s.conn, stagingTable)
+
+		return inserted, fmt.Errorf("cannot reload dictionary: %s", err)
+	}
+
+	s.logger.Info(fmt.Sprintf("Dropping previous source table data: %s", stagingTable))
+
+	return inserted, clickhouse_wrapper.DropTable(s.conn, stagingTable)
+}
+
+// Load the data into a staging copy of the source table, then replace each
+// partition of the source table with the one of the staging table. Partitions
+// absent from the data are dropped. Each partition is replaced atomically.
+func (s *Synchronizer) replacePartitionsSyncSourceTable(sourceTable string, reader dictionary_data.Reader) (uint64, error) {
+	stagingTable, inserted, err := s.loadStagingTable(sourceTable, reader)
+
+	if err != nil {
+		return inserted, err
+	}
+
+	defer clickhouse_wrapper.DropTable(s.conn, stagingTable)
+
+	stagingPartitions, err := clickhouse_wrapper.GetTablePartitions(s.conn, stagingTable)
+
+	if err != nil {
+		return inserted, fmt.Errorf("cannot get partitions of staging table: %s", err)
+	}
+
+	sourcePartitions, err := clickhouse_wrapper.GetTablePartitions(s.conn, sourceTable)
+
+	if err != nil {
+		return inserted, fmt.Errorf("cannot get partitions of source table: %s", err)
+	}
+
+	if err := s.backupSourceTable(sourceTable); err != nil {
+		return inserted, err
+	}
+
+	replaced := make(map[string]bool, len(stagingPartitions))
+
+	for _, partition := range stagingPartitions {
+		s.logger.Info(fmt.Sprintf("Replacing partition %s of source table: %s", partition, sourceTable))
+
+		if err := clickhouse_wrapper.ReplacePartition(s.conn, sourceTable, stagingTable, partition); err != nil {
+			return inserted, fmt.Errorf("cannot replace partition %s of source table: %s", partition, err)
+		}
+
+		replaced[partition] = true
+	}
+
+	for _, partition := range sourcePartitions {
+		if replaced[partition] {
+			continue
+		}
+
+		s.logger.Info(fmt.Sprintf("Dropping partition %s of source table: %s", partition, sourceTable))
+
+		if err := clickhouse_wrapper.DropPartition(s.conn, sourceTable, partition); err != nil {
+			return inserted, fmt.Errorf("cannot drop partition %s of source table: %s", partition, err)
+		}
+	}
+
+	s.logger.Info(fmt.Sprintf("Reloading dictionary: %s", s.options.DictionaryName))
+
+	if err := s.reloadDictionary(); err != nil {
+		return inserted, fmt.Errorf("cannot reload dictionary: %s", err)
+	}
+
+	count, err := s.countDictionaryRows(sourceTable, inserted)
+
+	if err != nil {
+		return inserted, err
+	}
+
+	return inserted, s.verifyDictionaryLoaded(count, true)
+}
+
+// Resolve the cleanup strategy of the source table, picking one from its
+// engine and the server version by default.
+func (s *Synchronizer) cleanupStrategy(sourceTable string) (string, error) {
+	if s.options.CleanupStrategy != "" && s.options.CleanupStrategy != clickhouse_wrapper.CleanupAuto {
+		return s.options.CleanupStrategy, nil
+	}
+
+	strategy, err := clickhouse_wrapper.DefaultCleanupStrategy(s.conn, sourceTable)
+
+	if err != nil {
+		return "", fmt.Errorf("cannot choose cleanup strategy of source table: %s", err)
+	}
+
+	return strategy, nil
+}
+
+// Load the data into a staging copy of the source table, and only apply the
+// rows which were added, updated or removed, compared by the dictionary key.
+// The dictionary is not reloaded when nothing changed.
+func (s *Synchronizer) incrementalSyncSourceTable(sourceTable string, reader dictionary_data.Reader) (uint64, error) {
+	keyNames, err := clickhouse_wrapper.GetDictionaryKeyNames(s.conn, s.options.DictionaryDatabase, s.options.DictionaryName)
+
+	if err != nil {
+		return 0, fmt.Errorf("cannot get dictionary key: %s", err)
+	}
+
+	tableColumns, err := clickhouse_wrapper.GetTableColumns(s.conn, sourceTable)
+
+	if err != nil {
+		return 0, fmt.Errorf("cannot read columns of %s: %s", sourceTable, err)
+	}
+
+	columns := make([]string, 0, len(tableColumns))
+
+	for _, column := range tableColumns {
+		if column.DefaultKind == "" || column.DefaultKind == "DEFAULT" {
+			columns = append(columns, column.Name)
+		}
+	}
+
+	stagingTable, inserted, err := s.loadStagingTable(sourceTable, reader)
+
+	if err != nil {
+		return inserted, err
+	}
+
+	defer clickhouse_wrapper.DropTable(s.conn, stagingTable)
+
+	diff, err := clickhouse_wrapper.DiffTables(s.conn, sourceTable, stagingTable, keyNames, columns, s.options.IsDeletedColumn)
+
+	if err != nil {
+		return inserted, err
+	}
+
+	fmt.Printf("%s: added: %d, updated: %d, removed: %d\n", s.options.Dictionary(), diff.Added, diff.Updated, diff.Removed)
+
+	if diff.IsEmpty() {
+		s.logger.Info("Source table is up to date, skipping dictionary reload")
+		return inserted, nil
+	}
+
+	if err := s.backupSourceTable(sourceTable); err != nil {
+		return inserted, err
+	}
+
+	s.logger.Info(fmt.Sprintf("Applying changes to source table: %s", sourceTable))
+
+	if err := clickhouse_wrapper.ApplyTableDiff(s.conn, sourceTable, stagingTable, keyNames, columns, s.options.IsDeletedColumn); err != nil {
+		return inserted, err
+	}
+
+	s.logger.Info(fmt.Sprintf("Reloading dictionary: %s", s.options.DictionaryName))
+
+	if err := s.reloadDictionary(); err != nil {
+		return inserted, fmt.Errorf("cannot reload dictionary: %s", err)
+	}
+
+	// Tombstones of ReplacingMergeTree tables are counted until merged
+	if s.options.IsDeletedColumn != "" {
+		return inserted, s.verifyDictionaryLoaded(0, false)
+	}
+
+	count, err := clickhouse_wrapper.CountRowsWhere(s.conn, sourceTable, s.source.Where)
+
+	if err != nil {
+		return inserted, fmt.Errorf("cannot count rows of source table: %s", err)
+	}
+
+	return inserted, s.verifyDictionaryLoaded(count, true)
+}
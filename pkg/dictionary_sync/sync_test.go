@@ -0,0 +1,152 @@
+package dictionary_sync
+
+import (
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSpoolSQLData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reference.db")
+	db, err := sql.Open("sqlite", path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Exec("CREATE TABLE countries (id INTEGER, name TEXT); INSERT INTO countries VALUES (1, 'France'), (2, NULL)")
+	db.Close()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := DefaultOptions
+	options.DictionaryDatabase = "default"
+	options.DictionaryName = "countries"
+	options.FromSQL = "sqlite://" + path
+	options.Query = "SELECT id, name FROM countries ORDER BY id"
+
+	data, cleanup, err := NewSynchronizer(nil, options).prepareData(false)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer cleanup()
+
+	if data.FromSQL != "" || data.Source == "" || data.Format != "ndjson" {
+		t.Fatalf("SQL data must be spooled to an NDJSON file, got source %q, format %q", data.Source, data.Format)
+	}
+
+	// The spooled rows do not change with the database
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	input, reader, err := data.OpenSource()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer input.Close()
+
+	names := make([]interface{}, 0)
+
+	for {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names = append(names, record["name"])
+	}
+
+	if len(names) != 2 || names[0] != "France" || names[1] != nil {
+		t.Errorf("names = %v, expected [France <nil>]", names)
+	}
+}
+
+func TestOptionsValidateLimitsWithoutValidation(t *testing.T) {
+	options := DefaultOptions
+	options.DictionaryDatabase = "default"
+	options.DictionaryName = "countries"
+	options.Source = "countries.csv"
+	options.MinRows = 10
+
+	if err := options.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	options.ValidateData = false
+
+	if err := options.Validate(); err == nil {
+		t.Error("expected an error for a minimum number of rows without validation")
+	}
+}
+
+func TestPrepareDataSpoolAlways(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "countries.csv")
+
+	if err := os.WriteFile(path, []byte("id,name\n1,France\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	options := DefaultOptions
+	options.DictionaryDatabase = "default"
+	options.DictionaryName = "countries"
+	options.Source = path
+
+	data, cleanup, err := NewSynchronizer(nil, options).prepareData(false)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cleanup()
+
+	if data.Source != path {
+		t.Errorf("local file must be read in place, got source %s", data.Source)
+	}
+
+	data, cleanup, err = NewSynchronizer(nil, options).prepareData(true)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer cleanup()
+
+	if data.Source == path || data.Format != "csv" {
+		t.Fatalf("local file must be spooled, got source %s, format %q", data.Source, data.Format)
+	}
+
+	// The spooled data does not change with the source
+	if err := os.WriteFile(path, []byte("id,name\n2,Germany\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	spooled, err := fileChecksum(data.Source)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := fileChecksum(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spooled == current {
+		t.Error("spooled data must keep the content read when prepared")
+	}
+}
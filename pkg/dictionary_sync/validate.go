@@ -0,0 +1,185 @@
+package dictionary_sync
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/clickhouse_wrapper"
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/dictionary_data"
+)
+
+// Number of problems listed by a validation report, the others being only
+// counted.
+const maxReportedProblems = 100
+
+// Problems found in the dictionary data before synchronizing it.
+type ValidationReport struct {
+	Rows         uint64
+	Problems     []string
+	ProblemCount int
+}
+
+func (r *ValidationReport) addProblem(format string, args ...interface{}) {
+	r.ProblemCount++
+
+	if len(r.Problems) < maxReportedProblems {
+		r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+	}
+}
+
+func (r *ValidationReport) IsValid() bool {
+	return r.ProblemCount == 0
+}
+
+// Error summarizing the problems of the report, nil when the data is valid.
+func (r *ValidationReport) Err() error {
+	if r.IsValid() {
+		return nil
+	}
+
+	message := strings.Join(r.Problems, "; ")
+
+	if r.ProblemCount > len(r.Problems) {
+		message = fmt.Sprintf("%s; and %d more", message, r.ProblemCount-len(r.Problems))
+	}
+
+	return fmt.Errorf("%d problems found: %s", r.ProblemCount, message)
+}
+
+// Read every record of the dictionary data and check that it can be written
+// to the source table: all keys are present, values convert to the column
+// types, dictionary keys are neither null nor duplicated, and the number of
+// rows stays within the configured limits.
+func (s *Synchronizer) validateData(sourceTable string, reader dictionary_data.Reader) (*ValidationReport, error) {
+	report := &ValidationReport{}
+	keys := reader.Keys()
+
+	converter, err := s.newValueConverter(sourceTable)
+
+	if err != nil {
+		return nil, err
+	}
+
+	keyNames, err := clickhouse_wrapper.GetDictionaryKeyNames(s.conn, s.options.DictionaryDatabase, s.options.DictionaryName)
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot get dictionary key: %s", err)
+	}
+
+	if _, err := clickhouse_wrapper.ValidateInsertColumns(s.conn, sourceTable, keys, s.optionalColumns()...); err != nil {
+		report.addProblem("%s", err)
+	}
+
+	dataKeys := make(map[string]bool)
+
+	for _, key := range keys {
+		dataKeys[key] = true
+	}
+
+	for _, keyName := range keyNames {
+		if !dataKeys[keyName] {
+			report.addProblem("dictionary key '%s' is missing from the data", keyName)
+		}
+	}
+
+	// Rows are identified by their dictionary key, its values being
+	// separated by a null byte
+	seenKeys := make(map[string]uint64)
+
+	for rowNumber := uint64(1); ; rowNumber++ {
+		values, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %s", rowNumber, err)
+		}
+
+		report.Rows++
+
+		converted := make(map[string]interface{}, len(keys))
+
+		for _, key := range keys {
+			value, ok := values[key]
+
+			if !ok {
+				report.addProblem("row %d: missing key '%s'", rowNumber, key)
+				continue
+			}
+
+			convertedValue, err := converter.convert(key, value)
+
+			if err != nil {
+				report.addProblem("row %d, column '%s': %s", rowNumber, key, err)
+				continue
+			}
+
+			converted[key] = convertedValue
+		}
+
+		keyValues := make([]string, 0, len(keyNames))
+		hasKey := true
+
+		for _, keyName := range keyNames {
+			value, ok := values[keyName]
+
+			if !ok {
+				hasKey = false
+				break
+			}
+
+			if value == nil || value == `\N` {
+				report.addProblem("row %d: null dictionary key '%s'", rowNumber, keyName)
+				hasKey = false
+				break
+			}
+
+			// Keys are compared once converted, so that `01` and `1` are
+			// the same integer key
+			if convertedValue, ok := converted[keyName]; ok {
+				value = convertedValue
+			}
+
+			keyValues = append(keyValues, fmt.Sprint(value))
+		}
+
+		if !hasKey {
+			continue
+		}
+
+		key := strings.Join(keyValues, "\x00")
+
+		if previousRow, ok := seenKeys[key]; ok {
+			report.addProblem("row %d: duplicate dictionary key (%s) of row %d", rowNumber, strings.Join(keyValues, ", "), previousRow)
+			continue
+		}
+
+		seenKeys[key] = rowNumber
+	}
+
+	if report.Rows < s.options.MinRows {
+		report.addProblem("data has %d rows, fewer than the minimum of %d", report.Rows, s.options.MinRows)
+	}
+
+	if s.options.MaxChangePercent > 0 {
+		currentRows, err := clickhouse_wrapper.CountRows(s.conn, sourceTable)
+
+		if err != nil {
+			return nil, fmt.Errorf("cannot count rows of source table: %s", err)
+		}
+
+		if currentRows > 0 {
+			change := math.Abs(float64(report.Rows)-float64(currentRows)) / float64(currentRows) * 100
+
+			if change > s.options.MaxChangePercent {
+				report.addProblem("data would change the number of rows from %d to %d (%.1f%%), more than the maximum of %g%%", currentRows, report.Rows, change, s.options.MaxChangePercent)
+			}
+		}
+	}
+
+	return report, nil
+}
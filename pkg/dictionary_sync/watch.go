@@ -0,0 +1,175 @@
+package dictionary_sync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/nwmqpa/clickhouse-toolbox/pkg/dictionary_data"
+)
+
+// Delay without further change of the source before synchronizing, as editors
+// and copies usually write a file through several events.
+const watchDebounce = time.Second
+
+// Checksum of the content of a file.
+func fileChecksum(path string) (string, error) {
+	input, err := os.Open(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer input.Close()
+
+	hash := sha256.New()
+
+	if _, err := io.Copy(hash, input); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Synchronize the dictionary, unless the content of its source did not change
+// since the given checksum of the last successful synchronization. Returns the
+// checksum of the synchronized source. The source is read once, into a
+// temporary file which is both checksummed and synchronized, so that URLs are
+// downloaded and SQL queries run once per check, and that the checksum
+// describes the synchronized data.
+func (s *Synchronizer) synchronizeIfChanged(lastChecksum string) (string, error) {
+	if err := s.options.Validate(); err != nil {
+		return lastChecksum, err
+	}
+
+	data, cleanup, err := s.prepareData(true)
+
+	if err != nil {
+		return lastChecksum, fmt.Errorf("cannot read dictionnary data: %s", err)
+	}
+
+	defer cleanup()
+
+	checksum, err := fileChecksum(data.Source)
+
+	if err != nil {
+		return lastChecksum, fmt.Errorf("cannot read dictionnary data: %s", err)
+	}
+
+	if checksum == lastChecksum {
+		s.logger.Info("Dictionary data did not change, skipping synchronization")
+		return lastChecksum, nil
+	}
+
+	start := time.Now()
+	rows, err := s.synchronizeData(data)
+
+	if err != nil {
+		return lastChecksum, err
+	}
+
+	s.logger.Info("Dictionary synchronized", "rows", rows, "duration", time.Since(start))
+
+	return checksum, nil
+}
+
+// Keep the dictionary synchronized until the context is done. The dictionary
+// is synchronized at once, then every time the source file changes when
+// watchFile is set, and on every interval when it is not zero. Failed
+// synchronizations are logged and retried on the next change or interval.
+func (s *Synchronizer) Watch(ctx context.Context, watchFile bool, interval time.Duration) error {
+	if s.options.Source == "-" {
+		return fmt.Errorf("cannot watch dictionary data read from stdin")
+	}
+
+	if !watchFile && interval <= 0 {
+		return fmt.Errorf("no file watch nor interval provided")
+	}
+
+	if watchFile && s.options.FromSQL != "" {
+		return fmt.Errorf("cannot watch dictionary data queried from a SQL database, use an interval instead")
+	}
+
+	if watchFile && dictionary_data.IsRemoteSource(s.options.Source) {
+		return fmt.Errorf("cannot watch dictionary data fetched from an URL, use an interval instead")
+	}
+
+	var fileEvents <-chan fsnotify.Event
+	var fileErrors <-chan error
+
+	source, err := filepath.Abs(s.options.Source)
+
+	if err != nil {
+		return err
+	}
+
+	if watchFile {
+		watcher, err := fsnotify.NewWatcher()
+
+		if err != nil {
+			return fmt.Errorf("cannot watch dictionary data: %s", err)
+		}
+
+		defer watcher.Close()
+
+		// The directory is watched rather than the file, so that files
+		// replaced through a rename keep being watched
+		if err := watcher.Add(filepath.Dir(source)); err != nil {
+			return fmt.Errorf("cannot watch dictionary data: %s", err)
+		}
+
+		fileEvents = watcher.Events
+		fileErrors = watcher.Errors
+
+		s.logger.Info(fmt.Sprintf("Watching dictionary data: %s", source))
+	}
+
+	var ticks <-chan time.Time
+
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		ticks = ticker.C
+
+		s.logger.Info(fmt.Sprintf("Synchronizing dictionary every %s", interval))
+	}
+
+	debounce := time.NewTimer(0)
+	defer debounce.Stop()
+
+	checksum := ""
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-fileEvents:
+			if filepath.Clean(event.Name) != source || event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			s.logger.Debug("Dictionary data changed", "event", event.Op.String())
+
+			debounce.Reset(watchDebounce)
+
+			continue
+		case err := <-fileErrors:
+			s.logger.Warn(fmt.Sprintf("Error watching dictionary data: %s", err))
+
+			continue
+		case <-ticks:
+		case <-debounce.C:
+		}
+
+		if checksum, err = s.synchronizeIfChanged(checksum); err != nil {
+			s.logger.Error(fmt.Sprintf("Error synchronizing dictionary: %s", err))
+		}
+	}
+}
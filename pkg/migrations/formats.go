@@ -0,0 +1,357 @@
+package migrations
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oriser/regroup"
+)
+
+// A MigrationFormat knows how migrations are laid out on disk by a given
+// migration tool, and how to read the SQL of each migration side.
+type MigrationFormat interface {
+	// Parse the given file of the migrations directory into the migrations it
+	// contains. Formats storing both sides in a single file return two
+	// migrations sharing the same path.
+	ParseFile(migrationDirectory, filename, identifier string) ([]Migration, error)
+	// Read the SQL content of the given migration.
+	ReadMigration(m *Migration) ([]byte, error)
+}
+
+const DefaultMigrationFormat = "toolbox"
+
+var MigrationFormats = map[string]MigrationFormat{
+	"toolbox":               toolboxFormat{},
+	"golang-migrate":        golangMigrateFormat{},
+	"goose":                 sectionFormat{upMarker: "-- +goose Up", downMarker: "-- +goose Down", annotationPrefix: "-- +goose"},
+	"dbmate":                sectionFormat{upMarker: "-- migrate:up", downMarker: "-- migrate:down", annotationPrefix: "-- migrate:"},
+	"clickhouse-migrations": upOnlyFormat{},
+}
+
+func GetMigrationFormat(name string) (MigrationFormat, error) {
+	format, ok := MigrationFormats[name]
+
+	if !ok {
+		names := make([]string, 0, len(MigrationFormats))
+
+		for formatName := range MigrationFormats {
+			names = append(names, formatName)
+		}
+
+		sort.Strings(names)
+
+		return nil, fmt.Errorf("unknown migrations format '%s': expected one of %s", name, strings.Join(names, ", "))
+	}
+
+	return format, nil
+}
+
+// Format of the migrations generated by `migrations create`, either split in
+// two files:
+// YYYY-MM-DD_HH-MM-SS_migration_name.{up|down}.sql
+// or in a single file with `-- +up` and `-- +down` sections:
+// YYYY-MM-DD_HH-MM-SS_migration_name.sql
+type toolboxFormat struct{}
+
+func (toolboxFormat) ParseFile(migrationDirectory, filename, identifier string) ([]Migration, error) {
+	if migrations, err := parseSingleFileMigrationFilename(migrationDirectory, filename, identifier); err == nil {
+		return migrations, nil
+	}
+
+	migration, err := parseMigrationFilename(migrationDirectory, filename, identifier)
+
+	if err != nil {
+		return nil, err
+	}
+
+	migration.Format = toolboxFormat{}
+
+	return []Migration{*migration}, nil
+}
+
+func (toolboxFormat) ReadMigration(m *Migration) ([]byte, error) {
+	return os.ReadFile(m.Path)
+}
+
+var singleFileFormat = sectionFormat{upMarker: SingleFileUpMarker, downMarker: SingleFileDownMarker}
+
+type singleFileMigrationName struct {
+	Datetime string `regroup:"datetime"`
+	Name     string `regroup:"name"`
+}
+
+func parseSingleFileMigrationFilename(migrationDirectory, filename, identifier string) ([]Migration, error) {
+	var migrationName singleFileMigrationName
+
+	if err := SingleFileMigrationFilenameRegex.MatchToTarget(filename, &migrationName); err != nil {
+		return nil, err
+	}
+
+	datetime, err := time.Parse("2006-01-02_15-04-05", migrationName.Datetime)
+
+	if err != nil {
+		return nil, err
+	}
+
+	migrationPath := filepath.Join(migrationDirectory, filename)
+
+	return []Migration{
+		{
+			Name:          migrationName.Name,
+			Path:          migrationPath,
+			Identifier:    identifier,
+			Datetime:      datetime,
+			MigrationSide: MigrationUp,
+			Format:        singleFileFormat,
+		},
+		{
+			Name:          migrationName.Name,
+			Path:          migrationPath,
+			Identifier:    identifier,
+			Datetime:      datetime,
+			MigrationSide: MigrationDown,
+			Format:        singleFileFormat,
+		},
+	}, nil
+}
+
+var GolangMigrateFilenameRegex = regroup.MustCompile(`^(?P<version>\d+)_(?P<name>[^.]*)\.(?P<side>down|up)\.sql$`)
+
+type golangMigrateFilename struct {
+	Version string `regroup:"version"`
+	Name    string `regroup:"name"`
+	Side    string `regroup:"side"`
+}
+
+// Format of golang-migrate: {version}_{name}.{up|down}.sql
+type golangMigrateFormat struct{}
+
+func (golangMigrateFormat) ParseFile(migrationDirectory, filename, identifier string) ([]Migration, error) {
+	var migrationName golangMigrateFilename
+
+	if err := GolangMigrateFilenameRegex.MatchToTarget(filename, &migrationName); err != nil {
+		return nil, err
+	}
+
+	datetime, err := ParseMigrationVersion(migrationName.Version)
+
+	if err != nil {
+		return nil, err
+	}
+
+	side := MigrationUp
+
+	if migrationName.Side == "down" {
+		side = MigrationDown
+	}
+
+	return []Migration{{
+		Name:          migrationName.Name,
+		Path:          filepath.Join(migrationDirectory, filename),
+		Identifier:    identifier,
+		Datetime:      datetime,
+		MigrationSide: side,
+		Format:        golangMigrateFormat{},
+	}}, nil
+}
+
+func (golangMigrateFormat) ReadMigration(m *Migration) ([]byte, error) {
+	return os.ReadFile(m.Path)
+}
+
+var VersionedFilenameRegex = regroup.MustCompile(`^(?P<version>\d+)_(?P<name>[^.]*)\.sql$`)
+
+type versionedFilename struct {
+	Version string `regroup:"version"`
+	Name    string `regroup:"name"`
+}
+
+// Format of clickhouse-migrations, which has no down migrations:
+// {version}_{name}.sql
+type upOnlyFormat struct{}
+
+func (upOnlyFormat) ParseFile(migrationDirectory, filename, identifier string) ([]Migration, error) {
+	var migrationName versionedFilename
+
+	if err := VersionedFilenameRegex.MatchToTarget(filename, &migrationName); err != nil {
+		return nil, err
+	}
+
+	datetime, err := ParseMigrationVersion(migrationName.Version)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []Migration{{
+		Name:          migrationName.Name,
+		Path:          filepath.Join(migrationDirectory, filename),
+		Identifier:    identifier,
+		Datetime:      datetime,
+		MigrationSide: MigrationUp,
+		Format:        upOnlyFormat{},
+	}}, nil
+}
+
+func (upOnlyFormat) ReadMigration(m *Migration) ([]byte, error) {
+	return os.ReadFile(m.Path)
+}
+
+// Format storing both sides of a migration in a single file, each side
+// starting at its marker line. Used by goose and dbmate:
+// {version}_{name}.sql
+type sectionFormat struct {
+	upMarker         string
+	downMarker       string
+	annotationPrefix string
+}
+
+func (f sectionFormat) ParseFile(migrationDirectory, filename, identifier string) ([]Migration, error) {
+	var migrationName versionedFilename
+
+	if err := VersionedFilenameRegex.MatchToTarget(filename, &migrationName); err != nil {
+		return nil, err
+	}
+
+	datetime, err := ParseMigrationVersion(migrationName.Version)
+
+	if err != nil {
+		return nil, err
+	}
+
+	migrationPath := filepath.Join(migrationDirectory, filename)
+
+	return []Migration{
+		{
+			Name:          migrationName.Name,
+			Path:          migrationPath,
+			Identifier:    identifier,
+			Datetime:      datetime,
+			MigrationSide: MigrationUp,
+			Format:        f,
+		},
+		{
+			Name:          migrationName.Name,
+			Path:          migrationPath,
+			Identifier:    identifier,
+			Datetime:      datetime,
+			MigrationSide: MigrationDown,
+			Format:        f,
+		},
+	}, nil
+}
+
+func (f sectionFormat) ReadMigration(m *Migration) ([]byte, error) {
+	data, err := os.ReadFile(m.Path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sections, err := splitSections(data, f.upMarker, f.downMarker, f.annotationPrefix)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid migration file %s: %s", m.Path, err)
+	}
+
+	return sections[m.MigrationSide], nil
+}
+
+// Split the content of a single-file migration into its up and down sections.
+// Lines starting with the annotation prefix (such as goose's StatementBegin)
+// are dropped.
+func splitSections(data []byte, upMarker, downMarker, annotationPrefix string) (map[MigrationSide][]byte, error) {
+	sections := map[MigrationSide]*bytes.Buffer{
+		MigrationUp:   {},
+		MigrationDown: {},
+	}
+
+	var current *bytes.Buffer
+	foundUp := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case isSectionMarker(trimmed, upMarker):
+			current = sections[MigrationUp]
+			foundUp = true
+		case isSectionMarker(trimmed, downMarker):
+			current = sections[MigrationDown]
+		case annotationPrefix != "" && strings.HasPrefix(trimmed, annotationPrefix):
+			continue
+		case current != nil:
+			current.WriteString(line)
+			current.WriteByte('\n')
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !foundUp {
+		return nil, fmt.Errorf("missing '%s' section marker", upMarker)
+	}
+
+	return map[MigrationSide][]byte{
+		MigrationUp:   sections[MigrationUp].Bytes(),
+		MigrationDown: sections[MigrationDown].Bytes(),
+	}, nil
+}
+
+// Whether a trimmed line is a section marker, possibly followed by options
+// such as dbmate's `transaction:false`. Comments merely starting like a
+// marker, such as `-- +updated` for a `-- +up` marker, are not markers.
+func isSectionMarker(line, marker string) bool {
+	rest, found := strings.CutPrefix(line, marker)
+
+	return found && (rest == "" || rest[0] == ' ' || rest[0] == '\t')
+}
+
+// Sequence numbers from which migration versions are Unix timestamps rather
+// than sequence numbers, once converted by ParseMigrationVersion.
+const sequenceVersionLimit = 1000000000
+
+// Convert the version prefix of other tools' migrations into a datetime, so
+// they can be ordered and stored like the toolbox's own migrations. Versions
+// of the form YYYYMMDDHHMMSS are parsed as such, other versions are sequence
+// numbers or Unix timestamps and are mapped to seconds since the Unix epoch.
+func ParseMigrationVersion(version string) (time.Time, error) {
+	if len(version) == 14 {
+		if datetime, err := time.Parse("20060102150405", version); err == nil {
+			return datetime, nil
+		}
+	}
+
+	sequence, err := strconv.ParseUint(version, 10, 64)
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid migration version '%s': %s", version, err)
+	}
+
+	// Datetimes of migrations are stored in a DateTime column
+	if sequence > math.MaxUint32 {
+		return time.Time{}, fmt.Errorf("invalid migration version '%s': too large to be stored as a datetime", version)
+	}
+
+	return time.Unix(int64(sequence), 0).UTC(), nil
+}
+
+// Whether the datetime of a migration was converted from a sequence number,
+// which is only ordered with the datetimes of other sequence numbers.
+func IsSequenceVersion(datetime time.Time) bool {
+	return datetime.Unix() < sequenceVersionLimit
+}
@@ -0,0 +1,119 @@
+package migrations
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMigrationVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected time.Time
+	}{
+		{"20240102030405", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"42", time.Unix(42, 0).UTC()},
+		{"1704164645", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"4294967295", time.Unix(4294967295, 0).UTC()},
+	}
+
+	for _, test := range tests {
+		datetime, err := ParseMigrationVersion(test.version)
+
+		if err != nil {
+			t.Errorf("ParseMigrationVersion(%s): %s", test.version, err)
+			continue
+		}
+
+		if !datetime.Equal(test.expected) {
+			t.Errorf("ParseMigrationVersion(%s) = %s, expected %s", test.version, datetime, test.expected)
+		}
+	}
+
+	for _, version := range []string{"", "v1", "4294967296", "18446744073709551616"} {
+		if _, err := ParseMigrationVersion(version); err == nil {
+			t.Errorf("ParseMigrationVersion(%s): expected an error", version)
+		}
+	}
+}
+
+func TestFindOutOfOrderMigrations(t *testing.T) {
+	sequence := func(version string) Migration {
+		datetime, err := ParseMigrationVersion(version)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return Migration{Name: version, Datetime: datetime}
+	}
+
+	pending := []Migration{sequence("3"), sequence("5"), sequence("20240101000000"), sequence("20250101000000")}
+
+	outOfOrder := FindOutOfOrderMigrations(pending, sequence("20241231000000").Datetime)
+
+	if len(outOfOrder) != 1 || outOfOrder[0].Name != "20240101000000" {
+		t.Errorf("out of order migrations = %v, expected only 20240101000000", outOfOrder)
+	}
+
+	outOfOrder = FindOutOfOrderMigrations(pending, sequence("4").Datetime)
+
+	if len(outOfOrder) != 1 || outOfOrder[0].Name != "3" {
+		t.Errorf("out of order migrations = %v, expected only 3", outOfOrder)
+	}
+}
+
+func TestSplitSections(t *testing.T) {
+	data := `-- +up
+-- +updated the schema in v2
+CREATE TABLE a (id UInt64) ENGINE = Memory;
+-- +downgrade notes
+-- +down
+DROP TABLE a;
+`
+
+	sections, err := splitSections([]byte(data), SingleFileUpMarker, SingleFileDownMarker, "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedUp := "-- +updated the schema in v2\nCREATE TABLE a (id UInt64) ENGINE = Memory;\n-- +downgrade notes\n"
+
+	if up := string(sections[MigrationUp]); up != expectedUp {
+		t.Errorf("up section = %q, expected %q", up, expectedUp)
+	}
+
+	if down := string(sections[MigrationDown]); down != "DROP TABLE a;\n" {
+		t.Errorf("down section = %q", down)
+	}
+}
+
+func TestSplitSectionsAnnotations(t *testing.T) {
+	data := `-- +goose Up
+-- +goose StatementBegin
+SELECT 1;
+-- +goose StatementEnd
+-- +goose Down
+SELECT 2;
+`
+
+	sections, err := splitSections([]byte(data), "-- +goose Up", "-- +goose Down", "-- +goose")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if up := string(sections[MigrationUp]); up != "SELECT 1;\n" {
+		t.Errorf("up section = %q", up)
+	}
+
+	if down := string(sections[MigrationDown]); down != "SELECT 2;\n" {
+		t.Errorf("down section = %q", down)
+	}
+}
+
+func TestSplitSectionsMissingUp(t *testing.T) {
+	if _, err := splitSections([]byte("-- +upgrade\nSELECT 1;\n"), SingleFileUpMarker, SingleFileDownMarker, ""); err == nil {
+		t.Error("expected an error for a missing up marker")
+	}
+}
@@ -0,0 +1,151 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// Tools whose bookkeeping table can be imported into the migrations table,
+// along with the name of the table they use by default.
+var HistorySourceTables = map[string]string{
+	"golang-migrate":        "schema_migrations",
+	"goose":                 "goose_db_version",
+	"clickhouse-migrations": "_migrations",
+}
+
+type HistoryImport struct {
+	// Migrations matching a version applied by the other tool.
+	Matched []Migration
+	// Versions applied by the other tool without a matching migration file.
+	Unmatched []string
+}
+
+func loadVersions(conn driver.Conn, query string) ([]uint64, error) {
+	rows, err := conn.Query(context.Background(), query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	versions := make([]uint64, 0)
+
+	for rows.Next() {
+		var version uint64
+
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, version)
+	}
+
+	return versions, rows.Err()
+}
+
+func loadGolangMigrateVersion(conn driver.Conn, database, table string) (uint64, error) {
+	row := conn.QueryRow(
+		context.Background(),
+		fmt.Sprintf("SELECT toUInt64(version), toUInt8(dirty) FROM %s.%s ORDER BY sequence DESC LIMIT 1", database, table),
+	)
+
+	var currentVersion uint64
+	var dirty uint8
+
+	if err := row.Scan(&currentVersion, &dirty); err != nil {
+		return 0, err
+	}
+
+	if dirty != 0 {
+		return 0, fmt.Errorf("golang-migrate version %d is dirty, fix it before importing the history", currentVersion)
+	}
+
+	return currentVersion, nil
+}
+
+// Read the versions applied by another migration tool from its bookkeeping
+// table, and match them against the loaded migrations.
+func ImportHistory(conn driver.Conn, source, database, table string, loadedMigrations []Migration) (*HistoryImport, error) {
+	var versions []uint64
+	var err error
+
+	// golang-migrate only records the current version, every migration up to
+	// it is considered applied.
+	var upToVersion *time.Time
+
+	switch source {
+	case "golang-migrate":
+		var currentVersion uint64
+
+		if currentVersion, err = loadGolangMigrateVersion(conn, database, table); err == nil {
+			versions = []uint64{currentVersion}
+
+			if datetime, err := ParseMigrationVersion(strconv.FormatUint(currentVersion, 10)); err == nil {
+				upToVersion = &datetime
+			}
+		}
+	case "goose":
+		versions, err = loadVersions(conn, fmt.Sprintf(
+			"SELECT toUInt64(version_id) FROM %s.%s WHERE version_id > 0 GROUP BY version_id HAVING argMax(is_applied, tstamp) = 1",
+			database,
+			table,
+		))
+	case "clickhouse-migrations":
+		versions, err = loadVersions(conn, fmt.Sprintf("SELECT DISTINCT toUInt64(version) FROM %s.%s", database, table))
+	default:
+		return nil, fmt.Errorf("unknown history source '%s': expected one of golang-migrate, goose, clickhouse-migrations", source)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s history from %s.%s: %s", source, database, table, err)
+	}
+
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	result := &HistoryImport{
+		Matched:   make([]Migration, 0),
+		Unmatched: make([]string, 0),
+	}
+
+	for _, version := range versions {
+		datetime, err := ParseMigrationVersion(strconv.FormatUint(version, 10))
+
+		if err != nil {
+			result.Unmatched = append(result.Unmatched, strconv.FormatUint(version, 10))
+			continue
+		}
+
+		found := false
+
+		for _, migration := range loadedMigrations {
+			if migration.MigrationSide == MigrationUp && migration.Datetime.Equal(datetime) {
+				result.Matched = append(result.Matched, migration)
+				found = true
+			}
+		}
+
+		if !found {
+			result.Unmatched = append(result.Unmatched, strconv.FormatUint(version, 10))
+		}
+	}
+
+	if upToVersion != nil {
+		for _, migration := range loadedMigrations {
+			if migration.MigrationSide == MigrationUp && migration.Datetime.Before(*upToVersion) {
+				result.Matched = append(result.Matched, migration)
+			}
+		}
+	}
+
+	sort.Slice(result.Matched, func(i, j int) bool {
+		return result.Matched[i].Datetime.Before(result.Matched[j].Datetime)
+	})
+
+	return result, nil
+}
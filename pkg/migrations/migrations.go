@@ -4,9 +4,12 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"io"
+	"io/fs"
 	"os"
+	"path"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -27,10 +30,19 @@ type Migration struct {
 	Identifier    string
 	Datetime      time.Time
 	MigrationSide MigrationSide
+	Format        MigrationFormat
 }
 
 var MigrationFilenameRegex = regroup.MustCompile(`^(?P<datetime>\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2})_(?P<name>[^.]*)\.(?P<side>down|up)\.sql$`)
 
+var SingleFileMigrationFilenameRegex = regroup.MustCompile(`^(?P<datetime>\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2})_(?P<name>[^.]*)\.sql$`)
+
+// Section markers of single-file migrations.
+const (
+	SingleFileUpMarker   = "-- +up"
+	SingleFileDownMarker = "-- +down"
+)
+
 type MigrationName struct {
 	Datetime string `regroup:"datetime"`
 	Name     string `regroup:"name"`
@@ -52,12 +64,12 @@ func parseMigrationFilename(migrationDirectory, filename, identifier string) (*M
 		return nil, err
 	}
 
-	path := fmt.Sprintf("%s%c%s", migrationDirectory, os.PathSeparator, filename)
+	migrationPath := filepath.Join(migrationDirectory, filename)
 
 	if migrationName.Side == "up" {
 		return &Migration{
 			Name:          migrationName.Name,
-			Path:          path,
+			Path:          migrationPath,
 			Datetime:      datetime,
 			Identifier:    identifier,
 			MigrationSide: MigrationUp,
@@ -65,7 +77,7 @@ func parseMigrationFilename(migrationDirectory, filename, identifier string) (*M
 	} else {
 		return &Migration{
 			Name:          migrationName.Name,
-			Path:          path,
+			Path:          migrationPath,
 			Datetime:      datetime,
 			Identifier:    identifier,
 			MigrationSide: MigrationDown,
@@ -73,8 +85,64 @@ func parseMigrationFilename(migrationDirectory, filename, identifier string) (*M
 	}
 }
 
-func LoadMigrationsDirectory(migrationDirectory, migrationIdentifier string) ([]Migration, error) {
-	dirEntries, err := os.ReadDir(migrationDirectory)
+// Name of the file, at the root of the migrations directory, listing glob
+// patterns of files to ignore when loading migrations.
+const MigrationIgnoreFilename = ".toolboxignore"
+
+func loadIgnorePatterns(migrationDirectory string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(migrationDirectory, MigrationIgnoreFilename))
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := make([]string, 0)
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if _, err := path.Match(line, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s' in %s: %s", line, MigrationIgnoreFilename, err)
+		}
+
+		patterns = append(patterns, line)
+	}
+
+	return patterns, nil
+}
+
+// Patterns are matched against both the path relative to the migrations
+// directory and the base name of the file.
+func isIgnored(relativePath string, patterns []string) bool {
+	relativePath = filepath.ToSlash(relativePath)
+	baseName := path.Base(relativePath)
+
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, relativePath); matched {
+			return true
+		}
+
+		if matched, _ := path.Match(pattern, baseName); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Load migrations from the given directory, skipping files which are not
+// `.sql` files or match a pattern of the `.toolboxignore` file. When recursive
+// is set, migrations of subdirectories are loaded as well.
+func LoadMigrationsDirectory(migrationDirectory, migrationIdentifier string, recursive bool, format MigrationFormat) ([]Migration, error) {
+	ignorePatterns, err := loadIgnorePatterns(migrationDirectory)
 
 	if err != nil {
 		return nil, err
@@ -82,18 +150,46 @@ func LoadMigrationsDirectory(migrationDirectory, migrationIdentifier string) ([]
 
 	migrations := make([]Migration, 0)
 
-	for _, dirEntry := range dirEntries {
+	err = filepath.WalkDir(migrationDirectory, func(filePath string, dirEntry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(migrationDirectory, filePath)
+
+		if err != nil {
+			return err
+		}
+
 		if dirEntry.IsDir() {
-			continue
+			if relativePath == "." {
+				return nil
+			}
+
+			if !recursive || isIgnored(relativePath, ignorePatterns) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if filepath.Ext(dirEntry.Name()) != ".sql" || isIgnored(relativePath, ignorePatterns) {
+			return nil
 		}
 
-		migration, err := parseMigrationFilename(migrationDirectory, dirEntry.Name(), migrationIdentifier)
+		fileMigrations, err := format.ParseFile(filepath.Dir(filePath), dirEntry.Name(), migrationIdentifier)
 
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("invalid migration file %s: %s", filePath, err)
 		}
 
-		migrations = append(migrations, *migration)
+		migrations = append(migrations, fileMigrations...)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
 	return migrations, nil
@@ -123,6 +219,61 @@ func SetupMigrationTable(conn driver.Conn, migrationDatabase, migrationTable, mi
 	))
 }
 
+type OutOfOrderPolicy string
+
+const (
+	OutOfOrderError OutOfOrderPolicy = "error"
+	OutOfOrderWarn  OutOfOrderPolicy = "warn"
+	OutOfOrderAllow OutOfOrderPolicy = "allow"
+)
+
+func ParseOutOfOrderPolicy(policy string) (OutOfOrderPolicy, error) {
+	switch OutOfOrderPolicy(policy) {
+	case OutOfOrderError, OutOfOrderWarn, OutOfOrderAllow:
+		return OutOfOrderPolicy(policy), nil
+	default:
+		return "", fmt.Errorf("unknown out-of-order policy '%s': expected one of error, warn, allow", policy)
+	}
+}
+
+// Returns the datetime of the newest applied migration for the given
+// identifier. The boolean is false when no migration has been applied yet.
+func GetLatestAppliedMigrationDatetime(conn driver.Conn, migrationDatabase, migrationTable, migrationIdentifier string) (time.Time, bool, error) {
+	row := conn.QueryRow(
+		context.Background(),
+		fmt.Sprintf("SELECT count(), max(datetime) FROM %s.%s WHERE identifier = ?", migrationDatabase, migrationTable),
+		migrationIdentifier,
+	)
+
+	var count uint64
+	var latest time.Time
+
+	if err := row.Scan(&count, &latest); err != nil {
+		return time.Time{}, false, err
+	}
+
+	return latest, count > 0, nil
+}
+
+// Returns the pending migrations which are older than the newest applied one,
+// and would thus be applied out of order. Migrations versioned by sequence
+// numbers are not compared with migrations versioned by datetimes.
+func FindOutOfOrderMigrations(pendingMigrations []Migration, latestApplied time.Time) []Migration {
+	outOfOrder := make([]Migration, 0)
+
+	for _, migration := range pendingMigrations {
+		if IsSequenceVersion(migration.Datetime) != IsSequenceVersion(latestApplied) {
+			continue
+		}
+
+		if migration.Datetime.Before(latestApplied) {
+			outOfOrder = append(outOfOrder, migration)
+		}
+	}
+
+	return outOfOrder
+}
+
 func (m *Migration) CheckIfMigrationIsApplied(conn driver.Conn, migrationDatabase, migrationTable string) (bool, error) {
 	rows, err := conn.Query(
 		context.Background(),
@@ -190,24 +341,29 @@ func (m *Migration) RemoveMigration(conn driver.Conn, migrationDatabase, migrati
 	)
 }
 
-func (m *Migration) ComputeChecksum() (string, error) {
-	file, err := os.Open(m.Path)
-	if err != nil {
-		return "", err
+// Read the SQL content of the migration, using the format it was loaded with.
+func (m *Migration) ReadContent() ([]byte, error) {
+	if m.Format == nil {
+		return os.ReadFile(m.Path)
 	}
-	defer file.Close()
 
-	hash := sha256.New()
+	return m.Format.ReadMigration(m)
+}
 
-	if _, err := io.Copy(hash, file); err != nil {
+func (m *Migration) ComputeChecksum() (string, error) {
+	content, err := m.ReadContent()
+
+	if err != nil {
 		return "", err
 	}
 
-	return hex.EncodeToString(hash.Sum(nil)), nil
+	hash := sha256.Sum256(content)
+
+	return hex.EncodeToString(hash[:]), nil
 }
 
 func (m *Migration) Apply(conn driver.Conn) error {
-	migrationData, err := os.ReadFile(m.Path)
+	migrationData, err := m.ReadContent()
 
 	if err != nil {
 		return err
@@ -239,7 +395,7 @@ func (m *Migration) Apply(conn driver.Conn) error {
 
 func (m *Migration) FindMatchingMigration(migrations []Migration) *Migration {
 	for _, migration := range migrations {
-		if migration.Name == m.Name && migration.MigrationSide != m.MigrationSide {
+		if migration.Name == m.Name && migration.Datetime.Equal(m.Datetime) && migration.MigrationSide != m.MigrationSide {
 			return &migration
 		}
 	}
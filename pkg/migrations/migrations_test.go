@@ -0,0 +1,132 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func writeMigrationFiles(t *testing.T, directory string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(directory, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func loadedMigrationPaths(t *testing.T, directory string, recursive bool) []string {
+	t.Helper()
+
+	migrations, err := LoadMigrationsDirectory(directory, "default", recursive, toolboxFormat{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := make([]string, 0, len(migrations))
+
+	for _, migration := range migrations {
+		relativePath, err := filepath.Rel(directory, migration.Path)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		paths = append(paths, filepath.ToSlash(relativePath))
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+func TestLoadMigrationsDirectory(t *testing.T) {
+	directory := t.TempDir()
+
+	writeMigrationFiles(t, directory, map[string]string{
+		MigrationIgnoreFilename:                    "# Work in progress\n*_draft.*\n\narchive\nnested/skipped\n",
+		"2024-01-01_00-00-00_create.up.sql":        "CREATE TABLE a (id UInt64) ENGINE = Memory;",
+		"2024-01-01_00-00-00_create.down.sql":      "DROP TABLE a;",
+		"2024-01-02_00-00-00_feature_draft.up.sql": "SELECT 1;",
+		"README.md":                                      "Migrations of the database",
+		"archive/2023-01-01_00-00-00_old.up.sql":         "SELECT 1;",
+		"nested/2024-01-03_00-00-00_nested.sql":          "-- +up\nSELECT 1;\n-- +down\nSELECT 2;\n",
+		"nested/notes.txt":                               "Not a migration",
+		"nested/skipped/2024-01-04_00-00-00_skip.up.sql": "SELECT 1;",
+	})
+
+	expected := []string{
+		"2024-01-01_00-00-00_create.down.sql",
+		"2024-01-01_00-00-00_create.up.sql",
+		"nested/2024-01-03_00-00-00_nested.sql",
+		"nested/2024-01-03_00-00-00_nested.sql",
+	}
+
+	if paths := loadedMigrationPaths(t, directory, true); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("recursively loaded %v, expected %v", paths, expected)
+	}
+
+	expected = []string{
+		"2024-01-01_00-00-00_create.down.sql",
+		"2024-01-01_00-00-00_create.up.sql",
+	}
+
+	if paths := loadedMigrationPaths(t, directory, false); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("loaded %v, expected %v", paths, expected)
+	}
+}
+
+func TestLoadMigrationsDirectoryWithoutIgnoreFile(t *testing.T) {
+	directory := t.TempDir()
+
+	writeMigrationFiles(t, directory, map[string]string{
+		"2024-01-02_00-00-00_feature_draft.up.sql": "SELECT 1;",
+	})
+
+	if paths := loadedMigrationPaths(t, directory, false); len(paths) != 1 {
+		t.Errorf("loaded %v, expected the draft migration", paths)
+	}
+}
+
+func TestLoadMigrationsDirectoryInvalidPattern(t *testing.T) {
+	directory := t.TempDir()
+
+	writeMigrationFiles(t, directory, map[string]string{
+		MigrationIgnoreFilename: "[unterminated\n",
+	})
+
+	if _, err := LoadMigrationsDirectory(directory, "default", false, toolboxFormat{}); err == nil {
+		t.Error("expected an error for an invalid ignore pattern")
+	}
+}
+
+func TestIsIgnored(t *testing.T) {
+	patterns := []string{"*.draft.sql", "legacy/*"}
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"2024-01-01_00-00-00_a.draft.sql", true},
+		{"nested/2024-01-01_00-00-00_a.draft.sql", true},
+		{"legacy/2024-01-01_00-00-00_a.up.sql", true},
+		{"nested/legacy/2024-01-01_00-00-00_a.up.sql", false},
+		{"2024-01-01_00-00-00_a.up.sql", false},
+	}
+
+	for _, test := range tests {
+		if ignored := isIgnored(filepath.FromSlash(test.path), patterns); ignored != test.expected {
+			t.Errorf("isIgnored(%s) = %t, expected %t", test.path, ignored, test.expected)
+		}
+	}
+}